@@ -1,4 +1,5 @@
 import type {
+  Bundle,
   Organization,
   HealthcareService,
   Questionnaire,
@@ -26,6 +27,11 @@ const getAuthHeaders = (includeContentTypeJson = true) => {
   return headers;
 };
 
+// --- Helper for Search Results ---
+// List endpoints return a FHIR searchset Bundle; unwrap the resources from its entries
+const bundleResources = <T>(bundle: Bundle<T>): T[] =>
+  (bundle.entry ?? []).map((entry) => entry.resource);
+
 // --- API Fetch Functions ---
 
 /**
@@ -44,7 +50,7 @@ export async function fetchProviders(baseUrl: string = "/api"): Promise<Organiza
       const errorBody = await response.text();
       throw new Error(`HTTP error! status: ${response.status} - ${response.statusText} - ${errorBody}`);
     }
-    const data: Organization[] = await response.json();
+    const data = bundleResources<Organization>(await response.json());
     console.log("Fetched Providers:", data); // Log fetched data
     return data;
   } catch (error) {
@@ -70,7 +76,7 @@ export async function fetchHealthcareServices(organizationId: string, baseUrl: s
       const errorBody = await response.text();
       throw new Error(`HTTP error! status: ${response.status} - ${response.statusText} - ${errorBody}`);
     }
-    const data: HealthcareService[] = await response.json();
+    const data = bundleResources<HealthcareService>(await response.json());
     console.log(`Fetched Services for ${organizationId}:`, data); // Log fetched data
     return data;
   } catch (error) {
@@ -95,7 +101,7 @@ export async function fetchQuestionnaires(baseUrl: string = "/api"): Promise<Que
       const errorBody = await response.text();
       throw new Error(`HTTP error! status: ${response.status} - ${response.statusText} - ${errorBody}`);
     }
-    const data: Questionnaire[] = await response.json();
+    const data = bundleResources<Questionnaire>(await response.json());
     console.log("Fetched Questionnaires:", data); // Log fetched data
     return data;
   } catch (error) {
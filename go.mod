@@ -6,6 +6,7 @@ require (
 	github.com/go-chi/chi/v5 v5.0.10
 	github.com/go-chi/cors v1.2.1
 	github.com/go-chi/render v1.0.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/ajg/form v1.5.1 // indirect
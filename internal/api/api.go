@@ -1,26 +1,60 @@
 package api
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/faults"
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/admin"
 	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/auth"
 	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/nurses"
 	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
 	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/quality"
+	"github.com/jasonchiu/dohac-mock-apis/internal/idgen"
+	"github.com/jasonchiu/dohac-mock-apis/internal/journal"
 	custommiddleware "github.com/jasonchiu/dohac-mock-apis/internal/middleware"
+	"github.com/jasonchiu/dohac-mock-apis/internal/proxy"
+	"github.com/jasonchiu/dohac-mock-apis/internal/scenarios"
+	"github.com/jasonchiu/dohac-mock-apis/internal/webhooks"
 )
 
 // NewRouter creates a new router with all the registered handlers
 func NewRouter() *chi.Mux {
 	r := chi.NewRouter()
 
+	// Virtual clock shared by all handlers, controlled by MOCK_CLOCK and /admin/clock
+	clk := clock.FromEnv()
+
+	// ID generator shared by all handlers, seeded by MOCK_ID_SEED
+	ids := idgen.FromEnv()
+
+	// Fault rules from MOCK_FAULTS_FILE; more can be added through /admin/faults
+	faults.LoadFromEnv()
+
+	// Scripted scenarios from MOCK_SCENARIOS, activated by MOCK_SCENARIO or the X-Mock-Scenario header
+	scenarios.LoadFromEnv()
+
+	// Request journal, sized by MOCK_JOURNAL_SIZE and queried through /admin/journal
+	journal.LoadFromEnv()
+
+	// Outbound webhooks from MOCK_WEBHOOKS_FILE; more can be added through /admin/webhooks
+	webhooks.LoadFromEnv(clk)
+
+	if admin.Enabled() {
+		log.Printf("Mock administration endpoints enabled at /api/admin")
+	}
+
 	// Middleware
 	r.Use(chimiddleware.RequestID)
 	r.Use(chimiddleware.RealIP)
+	r.Use(custommiddleware.Journal(clk))
+	r.Use(custommiddleware.TransactionID)
+	r.Use(custommiddleware.FederatedID)
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 	r.Use(render.SetContentType(render.ContentTypeJSON))
@@ -29,12 +63,26 @@ func NewRouter() *chi.Mux {
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "transaction_id"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", custommiddleware.TransactionIDHeader, custommiddleware.FederatedIDHeader, scenarios.Header},
+		ExposedHeaders:   []string{"Link", custommiddleware.TransactionIDHeader},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
 	r.Use(corsMiddleware.Handler)
+	r.Use(custommiddleware.Scenarios)
+	r.Use(custommiddleware.FaultInjection)
+
+	// In record and replay modes (MOCK_MODE) every API call goes to the upstream
+	// or the recordings instead of the mock handlers
+	if handler, mode, ok := proxy.FromEnv(clk); ok {
+		r.Get("/health", healthCheck)
+		if admin.Enabled() {
+			admin.RegisterHandlers(r, clk)
+		}
+		r.Handle("/*", handler)
+		log.Printf("Serving the API in %s mode", mode)
+		return r
+	}
 
 	// API routes - no '/api' prefix needed since the router will be mounted at /api
 	
@@ -44,21 +92,26 @@ func NewRouter() *chi.Mux {
 		r.Get("/health", healthCheck)
 
 		// Authentication endpoints
-		auth.RegisterHandlers(r)
+		auth.RegisterHandlers(r, clk, ids)
+
+		// Mock administration endpoints, only with MOCK_ADMIN=true
+		if admin.Enabled() {
+			admin.RegisterHandlers(r, clk)
+		}
 	})
 
 	// Protected routes that require authentication
 	r.Group(func(r chi.Router) {
-		r.Use(custommiddleware.AuthMiddleware)
+		r.Use(custommiddleware.AuthMiddleware(clk))
 
 		// Provider and Healthcare Service endpoints
 		provider.RegisterHandlers(r)
 
 		// Quality Indicators endpoints
-		quality.RegisterHandlers(r)
+		quality.RegisterHandlers(r, clk, ids)
 
 		// Registered Nurses endpoints
-		nurses.RegisterHandlers(r)
+		nurses.RegisterHandlers(r, clk, ids)
 	})
 
 	return r
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	custommiddleware "github.com/jasonchiu/dohac-mock-apis/internal/middleware"
+)
+
+// issueToken requests a client credentials token from a router
+func issueToken(t *testing.T, r http.Handler) string {
+	t.Helper()
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {"test"}}
+	req := httptest.NewRequest("POST", "/oauth2/access-tokens", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil || token.AccessToken == "" {
+		t.Fatalf("got %d %s, want a token", w.Code, w.Body)
+	}
+	return token.AccessToken
+}
+
+func TestSeededIDsIgnoreTransactionIDs(t *testing.T) {
+	t.Setenv("MOCK_ID_SEED", "42")
+	want := issueToken(t, NewRouter())
+
+	// Calls without a transaction_id header are given a generated one
+	r := NewRouter()
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+		if w.Header().Get(custommiddleware.TransactionIDHeader) == "" {
+			t.Fatal("no transaction_id was generated")
+		}
+	}
+	if got := issueToken(t, r); got != want {
+		t.Errorf("got token %s after untagged calls, want %s", got, want)
+	}
+}
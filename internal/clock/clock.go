@@ -0,0 +1,122 @@
+package clock
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Clock tells the handlers the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Virtual is a clock that can be set, frozen and advanced at runtime, so that
+// IDs, token expiry and reporting deadlines can be reproduced. Until it is
+// changed it follows the wall clock.
+type Virtual struct {
+	mu sync.RWMutex
+	// base is the virtual time at the wall time anchor
+	base   time.Time
+	anchor time.Time
+	frozen bool
+}
+
+// NewVirtual returns a virtual clock following the wall clock
+func NewVirtual() *Virtual {
+	now := time.Now()
+	return &Virtual{base: now, anchor: now}
+}
+
+// FromEnv returns a virtual clock frozen at MOCK_CLOCK (RFC 3339 or
+// YYYY-MM-DD), or following the wall clock when it is not set. The older
+// QI_CURRENT_DATE is accepted in its place.
+func FromEnv() *Virtual {
+	c := NewVirtual()
+	name, value := "MOCK_CLOCK", os.Getenv("MOCK_CLOCK")
+	if value == "" && os.Getenv("QI_CURRENT_DATE") != "" {
+		name, value = "QI_CURRENT_DATE", os.Getenv("QI_CURRENT_DATE")
+		log.Printf("QI_CURRENT_DATE is deprecated, use MOCK_CLOCK instead")
+	}
+	if value == "" {
+		return c
+	}
+	t, err := Parse(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s '%s': %v", name, value, err)
+		return c
+	}
+	c.Set(t, true)
+	log.Printf("Clock frozen at %s", t.Format(time.RFC3339))
+	return c
+}
+
+// Parse reads an RFC 3339 time or a YYYY-MM-DD date, taken as midnight UTC
+func Parse(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// Now returns the virtual time
+func (c *Virtual) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now()
+}
+
+func (c *Virtual) now() time.Time {
+	if c.frozen {
+		return c.base
+	}
+	return c.base.Add(time.Since(c.anchor))
+}
+
+// Frozen reports whether the clock is stopped
+func (c *Virtual) Frozen() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.frozen
+}
+
+// Set moves the clock to t, stopped or running from there
+func (c *Virtual) Set(t time.Time, frozen bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.anchor, c.frozen = t, time.Now(), frozen
+}
+
+// Advance moves the clock forward by d (backwards when d is negative)
+func (c *Virtual) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.anchor = c.now().Add(d), time.Now()
+}
+
+// Freeze stops the clock at the current virtual time
+func (c *Virtual) Freeze() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.anchor, c.frozen = c.now(), time.Now(), true
+}
+
+// Resume restarts a frozen clock from the time it was stopped at
+func (c *Virtual) Resume() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.anchor, c.frozen = c.now(), time.Now(), false
+}
+
+// Reset returns the clock to the wall clock
+func (c *Virtual) Reset() {
+	c.Set(time.Now(), false)
+}
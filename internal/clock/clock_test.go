@@ -0,0 +1,77 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	want := time.Date(2024, 10, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		mockClock     string
+		qiCurrentDate string
+		want          time.Time
+	}{
+		{"MOCK_CLOCK date", "2024-10-15", "", want},
+		{"MOCK_CLOCK time", "2024-10-15T00:00:00Z", "", want},
+		{"QI_CURRENT_DATE alias", "", "2024-10-15", want},
+		{"MOCK_CLOCK wins", "2024-10-15", "2023-01-01", want},
+	}
+	for _, tt := range tests {
+		t.Setenv("MOCK_CLOCK", tt.mockClock)
+		t.Setenv("QI_CURRENT_DATE", tt.qiCurrentDate)
+
+		c := FromEnv()
+		if !c.Frozen() {
+			t.Errorf("%s: clock is not frozen", tt.name)
+		}
+		if got := c.Now(); !got.Equal(tt.want) {
+			t.Errorf("%s: Now() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFromEnvUnsetOrInvalid(t *testing.T) {
+	for _, value := range []string{"", "15/10/2024"} {
+		t.Setenv("MOCK_CLOCK", value)
+		t.Setenv("QI_CURRENT_DATE", "")
+
+		c := FromEnv()
+		if c.Frozen() {
+			t.Errorf("MOCK_CLOCK=%q: clock is frozen", value)
+		}
+		if since := time.Since(c.Now()); since < 0 || since > time.Minute {
+			t.Errorf("MOCK_CLOCK=%q: Now() is %s away from the wall clock", value, since)
+		}
+	}
+}
+
+func TestVirtual(t *testing.T) {
+	start := time.Date(2025, 1, 21, 12, 0, 0, 0, time.UTC)
+	c := NewVirtual()
+	c.Set(start, true)
+
+	c.Advance(36 * time.Hour)
+	if got, want := c.Now(), start.Add(36*time.Hour); !got.Equal(want) {
+		t.Errorf("after Advance: Now() = %s, want %s", got, want)
+	}
+
+	c.Resume()
+	if c.Frozen() || c.Now().Before(start.Add(36*time.Hour)) {
+		t.Errorf("after Resume: frozen %v at %s", c.Frozen(), c.Now())
+	}
+
+	c.Freeze()
+	stopped := c.Now()
+	time.Sleep(time.Millisecond)
+	if !c.Now().Equal(stopped) {
+		t.Error("frozen clock moved")
+	}
+
+	c.Reset()
+	if c.Frozen() || time.Since(c.Now()) > time.Minute {
+		t.Errorf("after Reset: frozen %v at %s", c.Frozen(), c.Now())
+	}
+}
@@ -0,0 +1,214 @@
+package faults
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+)
+
+// injectableStatuses are the error responses a rule may inject
+var injectableStatuses = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// Store of fault rules, checked in order
+var (
+	mu     sync.Mutex
+	rules  []models.FaultRule
+	nextID = 1
+)
+
+// Validate checks that a rule has a route, at least one fault and sensible settings
+func Validate(rule models.FaultRule) error {
+	if !strings.HasPrefix(rule.Route, "/") {
+		return fmt.Errorf("route must be a path pattern starting with '/'")
+	}
+	if rule.Latency != "" {
+		if d, err := time.ParseDuration(rule.Latency); err != nil || d < 0 {
+			return fmt.Errorf("invalid latency '%s', must be a duration such as \"2s\"", rule.Latency)
+		}
+	}
+	if rule.Status != 0 && !injectableStatuses[rule.Status] {
+		return fmt.Errorf("status must be one of 429, 500, 502, 503 or 504")
+	}
+	if rule.Probability != nil && (*rule.Probability < 0 || *rule.Probability > 1) {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	if rule.Nth < 0 || rule.Times < 0 || rule.RetryAfter < 0 {
+		return fmt.Errorf("nth, times and retryAfter cannot be negative")
+	}
+	if rule.Latency == "" && rule.Status == 0 && !rule.Drop && !rule.Malformed {
+		return fmt.Errorf("a rule needs at least one of latency, status, drop or malformed")
+	}
+	return nil
+}
+
+// Add validates and stores a rule, returning it with its assigned ID
+func Add(rule models.FaultRule) (models.FaultRule, error) {
+	if err := Validate(rule); err != nil {
+		return rule, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	taken := map[string]bool{}
+	for _, existing := range rules {
+		taken[existing.ID] = true
+	}
+	if taken[rule.ID] {
+		return rule, fmt.Errorf("fault rule %s already exists", rule.ID)
+	}
+	rule = prepare(rule, taken)
+	rules = append(rules, rule)
+	return rule, nil
+}
+
+// Replace validates rules and replaces all stored rules with them
+func Replace(newRules []models.FaultRule) ([]models.FaultRule, error) {
+	taken := map[string]bool{}
+	for i, rule := range newRules {
+		if err := Validate(rule); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if rule.ID == "" {
+			continue
+		}
+		if taken[rule.ID] {
+			return nil, fmt.Errorf("rule %d: fault rule %s appears more than once", i+1, rule.ID)
+		}
+		taken[rule.ID] = true
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	rules = nil
+	for _, rule := range newRules {
+		rules = append(rules, prepare(rule, taken))
+	}
+	return list(), nil
+}
+
+// prepare assigns an ID not in taken and resets the counters, adding the ID to
+// taken. Callers must hold mu.
+func prepare(rule models.FaultRule, taken map[string]bool) models.FaultRule {
+	if rule.ID == "" {
+		// Skip generated IDs already given to other rules
+		for taken[fmt.Sprintf("fault-%d", nextID)] {
+			nextID++
+		}
+		rule.ID = fmt.Sprintf("fault-%d", nextID)
+		nextID++
+	}
+	taken[rule.ID] = true
+	rule.Method = strings.ToUpper(rule.Method)
+	rule.Calls, rule.Injected = 0, 0
+	return rule
+}
+
+// list returns copies of the stored rules. Callers must hold mu.
+func list() []models.FaultRule {
+	return append([]models.FaultRule{}, rules...)
+}
+
+// Rules returns copies of the stored rules with their counters
+func Rules() []models.FaultRule {
+	mu.Lock()
+	defer mu.Unlock()
+	return list()
+}
+
+// Delete removes a rule, reporting whether it existed
+func Delete(id string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, rule := range rules {
+		if rule.ID == id {
+			rules = append(rules[:i], rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes every rule
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	rules = nil
+}
+
+// LoadFromEnv loads the rules in the file named by MOCK_FAULTS_FILE, if set
+func LoadFromEnv() {
+	path := os.Getenv("MOCK_FAULTS_FILE")
+	if path == "" {
+		return
+	}
+	if err := LoadFile(path); err != nil {
+		log.Printf("Ignoring MOCK_FAULTS_FILE: %v", err)
+		return
+	}
+	log.Printf("Loaded %d fault rule(s) from %s", len(Rules()), path)
+}
+
+// LoadFile replaces the rules with the JSON array of rules in a file
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fileRules []models.FaultRule
+	if err := json.Unmarshal(data, &fileRules); err != nil {
+		return fmt.Errorf("invalid fault rules in %s: %w", path, err)
+	}
+	_, err = Replace(fileRules)
+	return err
+}
+
+// Next counts a call against every rule matching its method and route, and
+// returns the first rule that fires for it
+func Next(method, route string) (models.FaultRule, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var fired *models.FaultRule
+	for i := range rules {
+		rule := &rules[i]
+		if (rule.Method != "" && rule.Method != method) || !routematch.Match(rule.Route, route) {
+			continue
+		}
+		rule.Calls++
+		if fired == nil && fires(*rule) {
+			rule.Injected++
+			fired = rule
+		}
+	}
+	if fired == nil {
+		return models.FaultRule{}, false
+	}
+	return *fired, true
+}
+
+// fires decides whether a rule injects its fault into the current call
+func fires(rule models.FaultRule) bool {
+	if rule.Times > 0 && rule.Injected >= rule.Times {
+		return false
+	}
+	if rule.Nth > 0 {
+		return rule.Calls%rule.Nth == 0
+	}
+	if rule.Probability == nil {
+		return true
+	}
+	return rand.Float64() < *rule.Probability
+}
@@ -0,0 +1,171 @@
+package faults
+
+import (
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+func probability(p float64) *float64 { return &p }
+
+// reset clears the stored rules and restarts the generated IDs
+func reset() {
+	Clear()
+	nextID = 1
+}
+
+// fired counts the faults injected into n calls
+func fired(method, route string, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if _, ok := Next(method, route); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestNextMatching(t *testing.T) {
+	reset()
+	defer reset()
+	if _, err := Add(models.FaultRule{Method: "post", Route: "/QuestionnaireResponse/{id}", Status: 503}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, route string
+		fires         bool
+	}{
+		{"POST", "/QuestionnaireResponse/QR-1", true},
+		{"GET", "/QuestionnaireResponse/QR-1", false},
+		{"POST", "/QuestionnaireResponse", false},
+		{"POST", "/QuestionnaireResponse/QR-1/history", false},
+		{"POST", "/Provider/QR-1", false},
+	}
+	for _, tt := range tests {
+		rule, ok := Next(tt.method, tt.route)
+		if ok != tt.fires {
+			t.Errorf("%s %s: fired %v, want %v", tt.method, tt.route, ok, tt.fires)
+		}
+		if ok && rule.Status != 503 {
+			t.Errorf("%s %s: got status %d, want 503", tt.method, tt.route, rule.Status)
+		}
+	}
+	if rules := Rules(); rules[0].Calls != 1 || rules[0].Injected != 1 {
+		t.Errorf("got %d calls and %d injected, want 1 and 1", rules[0].Calls, rules[0].Injected)
+	}
+}
+
+func TestNextNthAndTimes(t *testing.T) {
+	reset()
+	defer reset()
+	Add(models.FaultRule{Route: "/Provider/*", Status: 500, Nth: 3, Times: 2})
+
+	var got []bool
+	for i := 0; i < 9; i++ {
+		_, ok := Next("GET", "/Provider/PRV-1")
+		got = append(got, ok)
+	}
+	want := []bool{false, false, true, false, false, true, false, false, false}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNextProbability(t *testing.T) {
+	tests := []struct {
+		name        string
+		probability *float64
+		min, max    int
+	}{
+		{"omitted", nil, 1000, 1000},
+		{"one", probability(1), 1000, 1000},
+		{"zero", probability(0), 0, 0},
+		{"half", probability(0.5), 350, 650},
+	}
+	for _, tt := range tests {
+		reset()
+		Add(models.FaultRule{Route: "/Provider", Status: 502, Probability: tt.probability})
+		if n := fired("GET", "/Provider", 1000); n < tt.min || n > tt.max {
+			t.Errorf("probability %s: fired %d of 1000 calls, want %d to %d", tt.name, n, tt.min, tt.max)
+		}
+	}
+	reset()
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule models.FaultRule
+		ok   bool
+	}{
+		{"status", models.FaultRule{Route: "/Provider", Status: 503}, true},
+		{"zero probability", models.FaultRule{Route: "/Provider", Drop: true, Probability: probability(0)}, true},
+		{"no route", models.FaultRule{Route: "Provider", Status: 503}, false},
+		{"no fault", models.FaultRule{Route: "/Provider"}, false},
+		{"status not injectable", models.FaultRule{Route: "/Provider", Status: 404}, false},
+		{"probability above 1", models.FaultRule{Route: "/Provider", Status: 503, Probability: probability(1.5)}, false},
+		{"negative probability", models.FaultRule{Route: "/Provider", Status: 503, Probability: probability(-0.1)}, false},
+		{"bad latency", models.FaultRule{Route: "/Provider", Latency: "soon"}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.rule); (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestAddIDs(t *testing.T) {
+	reset()
+	defer reset()
+
+	// A user ID that the next generated ID would repeat
+	if _, err := Add(models.FaultRule{ID: "fault-1", Route: "/Provider", Drop: true}); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := Add(models.FaultRule{Route: "/Provider", Drop: true})
+	if err != nil || generated.ID != "fault-2" {
+		t.Errorf("got ID %s (%v), want fault-2", generated.ID, err)
+	}
+	if _, err := Add(models.FaultRule{ID: "fault-2", Route: "/Provider", Drop: true}); err == nil {
+		t.Error("added a second fault-2")
+	}
+
+	if _, err := Replace([]models.FaultRule{
+		{ID: "slow", Route: "/Provider", Latency: "1s"},
+		{ID: "slow", Route: "/Provider", Drop: true},
+	}); err == nil {
+		t.Error("replaced with two rules with the same ID")
+	}
+
+	reset()
+	replaced, err := Replace([]models.FaultRule{
+		{Route: "/Provider", Drop: true},
+		{ID: "fault-1", Route: "/Provider", Drop: true},
+	})
+	if err != nil || replaced[0].ID == replaced[1].ID {
+		t.Errorf("got rules %v (%v), want unique IDs", replaced, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	reset()
+	defer reset()
+	first, _ := Add(models.FaultRule{Route: "/Provider", Status: 500})
+	second, _ := Add(models.FaultRule{Route: "/Provider", Status: 503})
+
+	if !Delete(first.ID) {
+		t.Fatalf("%s was not deleted", first.ID)
+	}
+	if Delete(first.ID) {
+		t.Errorf("%s was deleted twice", first.ID)
+	}
+	if rules := Rules(); len(rules) != 1 || rules[0].ID != second.ID {
+		t.Errorf("got rules %v, want only %s", rules, second.ID)
+	}
+	if rule, ok := Next("GET", "/Provider"); !ok || rule.Status != 503 {
+		t.Errorf("got %v %v, want the remaining 503 rule to fire", rule, ok)
+	}
+}
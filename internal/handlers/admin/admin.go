@@ -0,0 +1,439 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/faults"
+	"github.com/jasonchiu/dohac-mock-apis/internal/journal"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/scenarios"
+	"github.com/jasonchiu/dohac-mock-apis/internal/webhooks"
+)
+
+// clk is the server's virtual clock, controlled by the /admin/clock endpoints
+var clk = clock.NewVirtual()
+
+// Enabled reports whether the administration endpoints are served. They are
+// unauthenticated and change the server for every caller, and the webhooks make
+// it post to any URL, so they are only mounted when MOCK_ADMIN=true.
+func Enabled() bool {
+	return os.Getenv("MOCK_ADMIN") == "true"
+}
+
+// RegisterHandlers registers the mock administration endpoints
+func RegisterHandlers(r chi.Router, c *clock.Virtual) {
+	clk = c
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Route("/clock", func(r chi.Router) {
+			r.Get("/", getClock)
+			r.Put("/", setClock)
+			r.Post("/advance", advanceClock)
+			r.Post("/freeze", freezeClock)
+			r.Post("/resume", resumeClock)
+			r.Delete("/", resetClock)
+		})
+		r.Route("/faults", func(r chi.Router) {
+			r.Get("/", getFaults)
+			r.Post("/", addFault)
+			r.Put("/", replaceFaults)
+			r.Delete("/", clearFaults)
+			r.Delete("/{id}", deleteFault)
+		})
+		r.Route("/scenarios", func(r chi.Router) {
+			r.Get("/", getScenarios)
+			r.Post("/", putScenarios)
+			r.Put("/active", activateScenario)
+			r.Delete("/active", deactivateScenario)
+			r.Get("/{name}", getScenario)
+			r.Delete("/{name}", deleteScenario)
+			r.Post("/{name}/reset", resetScenario)
+		})
+		r.Route("/journal", func(r chi.Router) {
+			r.Get("/", getJournal)
+			r.Delete("/", clearJournal)
+			r.Get("/{id}", getJournalEntry)
+		})
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Get("/", getWebhooks)
+			r.Post("/", addWebhook)
+			r.Delete("/", clearWebhooks)
+			r.Get("/deliveries", getDeliveries)
+			r.Delete("/deliveries", clearDeliveries)
+			r.Get("/deliveries/{id}", getDelivery)
+			r.Delete("/{id}", deleteWebhook)
+		})
+	})
+}
+
+// getClock returns the current virtual time
+func getClock(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, clockState())
+}
+
+// setClock moves the clock to a time, frozen unless "frozen": false is given
+func setClock(w http.ResponseWriter, r *http.Request) {
+	var req models.ClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body"))
+		return
+	}
+
+	t, err := clock.Parse(req.Now)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "now must be an RFC 3339 time or a YYYY-MM-DD date"))
+		return
+	}
+
+	frozen := req.Frozen == nil || *req.Frozen
+	clk.Set(t, frozen)
+	log.Printf("Clock set to %s (frozen: %v)", t.Format(time.RFC3339), frozen)
+	render.JSON(w, r, clockState())
+}
+
+// advanceClock moves the clock forward by a duration
+func advanceClock(w http.ResponseWriter, r *http.Request) {
+	var req models.ClockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body"))
+		return
+	}
+
+	d, err := time.ParseDuration(req.Advance)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "advance must be a duration such as \"72h\""))
+		return
+	}
+
+	clk.Advance(d)
+	log.Printf("Clock advanced by %s to %s", d, clk.Now().Format(time.RFC3339))
+	render.JSON(w, r, clockState())
+}
+
+// freezeClock stops the clock at the current virtual time
+func freezeClock(w http.ResponseWriter, r *http.Request) {
+	clk.Freeze()
+	render.JSON(w, r, clockState())
+}
+
+// resumeClock restarts the clock from the time it was frozen at
+func resumeClock(w http.ResponseWriter, r *http.Request) {
+	clk.Resume()
+	render.JSON(w, r, clockState())
+}
+
+// resetClock returns the clock to the wall clock
+func resetClock(w http.ResponseWriter, r *http.Request) {
+	clk.Reset()
+	log.Printf("Clock reset to the system time")
+	render.JSON(w, r, clockState())
+}
+
+func clockState() models.ClockState {
+	return models.ClockState{Now: clk.Now(), Frozen: clk.Frozen()}
+}
+
+// getFaults lists the fault injection rules with their call counters
+func getFaults(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, faults.Rules())
+}
+
+// addFault adds a fault injection rule
+func addFault(w http.ResponseWriter, r *http.Request) {
+	var rule models.FaultRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body"))
+		return
+	}
+
+	rule, err := faults.Add(rule)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	log.Printf("Added fault rule %s for %s", rule.ID, strings.TrimSpace(rule.Method+" "+rule.Route))
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, rule)
+}
+
+// replaceFaults replaces all fault injection rules
+func replaceFaults(w http.ResponseWriter, r *http.Request) {
+	var rules []models.FaultRule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body, expected an array of fault rules"))
+		return
+	}
+
+	stored, err := faults.Replace(rules)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+	render.JSON(w, r, stored)
+}
+
+// clearFaults removes every fault injection rule
+func clearFaults(w http.ResponseWriter, r *http.Request) {
+	faults.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteFault removes a fault injection rule
+func deleteFault(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !faults.Delete(id) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Fault rule "+id+" not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getScenarios lists the scenarios with their step counters
+func getScenarios(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, scenarios.List())
+}
+
+// putScenarios adds one scenario or a list of them, as JSON or YAML
+// (Content-Type: application/yaml), replacing those with the same names
+func putScenarios(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body"))
+		return
+	}
+
+	list, err := scenarios.Parse(data, scenarios.IsYAML(r.Header.Get("Content-Type")))
+	if err == nil {
+		err = scenarios.Put(list)
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	stored := []models.Scenario{}
+	for _, s := range list {
+		scenario, _ := scenarios.Get(s.Name)
+		stored = append(stored, scenario)
+		log.Printf("Stored scenario %s with %d step(s)", s.Name, len(s.Steps))
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, stored)
+}
+
+// getScenario returns a scenario by name
+func getScenario(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	scenario, ok := scenarios.Get(name)
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Scenario "+name+" not found"))
+		return
+	}
+	render.JSON(w, r, scenario)
+}
+
+// deleteScenario removes a scenario
+func deleteScenario(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !scenarios.Delete(name) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Scenario "+name+" not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetScenario sets a scenario's step counters back to zero
+func resetScenario(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !scenarios.Reset(name) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Scenario "+name+" not found"))
+		return
+	}
+	scenario, _ := scenarios.Get(name)
+	render.JSON(w, r, scenario)
+}
+
+// activateScenario applies a scenario to requests without an X-Mock-Scenario header
+func activateScenario(w http.ResponseWriter, r *http.Request) {
+	var req models.ScenarioActivation
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "name is required"))
+		return
+	}
+	if err := scenarios.Activate(req.Name); err != nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", err.Error()))
+		return
+	}
+	log.Printf("Activated scenario %s", req.Name)
+	scenario, _ := scenarios.Get(req.Name)
+	render.JSON(w, r, scenario)
+}
+
+// deactivateScenario stops applying the active scenario
+func deactivateScenario(w http.ResponseWriter, r *http.Request) {
+	scenarios.Activate("")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getJournal lists the recorded API calls, oldest first, filtered by method,
+// path (a chi route pattern relative to /api), status, transaction_id, since
+// (RFC 3339 or YYYY-MM-DD) and limit (the most recent N entries)
+func getJournal(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := journal.Filter{
+		Method:        strings.ToUpper(query.Get("method")),
+		Route:         query.Get("path"),
+		TransactionID: query.Get("transaction_id"),
+	}
+
+	var err error
+	if value := query.Get("status"); value != "" {
+		if filter.Status, err = strconv.Atoi(value); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "status must be an HTTP status code"))
+			return
+		}
+	}
+	if value := query.Get("since"); value != "" {
+		if filter.Since, err = clock.Parse(value); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "since must be an RFC 3339 time or a YYYY-MM-DD date"))
+			return
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "limit must be a positive number"))
+			return
+		}
+	}
+
+	render.JSON(w, r, journal.List(filter))
+}
+
+// getJournalEntry returns a recorded API call by ID
+func getJournalEntry(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	entry, ok := journal.Get(id)
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Journal entry "+chi.URLParam(r, "id")+" not found"))
+		return
+	}
+	render.JSON(w, r, entry)
+}
+
+// clearJournal removes every recorded API call
+func clearJournal(w http.ResponseWriter, r *http.Request) {
+	journal.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getWebhooks lists the webhooks
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, webhooks.Webhooks())
+}
+
+// addWebhook adds a webhook
+func addWebhook(w http.ResponseWriter, r *http.Request) {
+	var hook models.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body"))
+		return
+	}
+
+	hook, err := webhooks.Add(hook)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	log.Printf("Added webhook %s for %s", hook.ID, hook.URL)
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, hook)
+}
+
+// clearWebhooks removes every webhook
+func clearWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteWebhook removes a webhook
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !webhooks.Delete(id) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Webhook "+id+" not found"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getDeliveries lists the recent webhook deliveries and their attempts, oldest
+// first, filtered by webhook, event, status and limit (the most recent N)
+func getDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := webhooks.DeliveryFilter{
+		WebhookID: query.Get("webhook"),
+		Event:     query.Get("event"),
+		Status:    query.Get("status"),
+	}
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "limit must be a positive number"))
+			return
+		}
+	}
+	render.JSON(w, r, webhooks.Deliveries(filter))
+}
+
+// getDelivery returns a webhook delivery by ID
+func getDelivery(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+	delivery, ok := webhooks.Delivery(id)
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Webhook delivery "+chi.URLParam(r, "id")+" not found"))
+		return
+	}
+	render.JSON(w, r, delivery)
+}
+
+// clearDeliveries forgets the recorded webhook deliveries
+func clearDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhooks.ClearDeliveries()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -7,15 +7,32 @@ import (
 	"io"  // Added import
 	"log" // Added import
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/idgen"
 	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
+	"github.com/jasonchiu/dohac-mock-apis/internal/webhooks"
+)
+
+var organizationPattern = regexp.MustCompile(`^PRV-\d+$`)
+
+// clk times issued tokens and ids generates access tokens and client IDs
+var (
+	clk = clock.System
+	ids = idgen.New()
 )
 
 // RegisterHandlers registers the authentication handlers
-func RegisterHandlers(r chi.Router) {
+func RegisterHandlers(r chi.Router, c clock.Clock, g *idgen.Generator) {
+	clk, ids = c, g
+
 	r.Route("/oauth2", func(r chi.Router) {
 		r.Post("/access-tokens", createAccessToken)
 		r.Post("/registration", registerClient)
@@ -76,19 +93,67 @@ func createAccessToken(w http.ResponseWriter, r *http.Request) {
 	// In a real implementation, we would validate the client credentials or JWT
 	// For this mock, we'll just return a success response
 
+	// Bind the token to the organisations named in the scope
+	organisations, restricted, err := scopeOrganisations(req.Scope)
+	if err != nil {
+		log.Printf("createAccessToken: Invalid scope: %v. Request: %+v", err, req)
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]string{"error": "invalid_scope", "error_description": err.Error()})
+		return
+	}
+
 	// Create mock token response
+	issuedAt := clk.Now()
 	resp := models.TokenResponse{
-		AccessToken: "mock_" + req.ClientID + "_" + time.Now().Format("20060102150405"),
+		AccessToken: "mock_" + req.ClientID + "_" + ids.UUID(),
 		TokenType:   "Bearer",
 		ExpiresIn:   3600,
 		Scope:       req.Scope,
 	}
-	log.Printf("createAccessToken: Response Payload: %+v", resp)
+	tokens.Save(resp.AccessToken, tokens.Claims{
+		ClientID:      req.ClientID,
+		Restricted:    restricted,
+		Organisations: organisations,
+		IssuedAt:      issuedAt,
+		ExpiresAt:     issuedAt.Add(time.Duration(resp.ExpiresIn) * time.Second),
+	})
+	log.Printf("createAccessToken: Response Payload: %+v, Organisations: %v", resp, organisations)
 
 	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, resp)
 }
 
+// scopeOrganisations returns the provider (PRV-) IDs a token is bound to. Organisations
+// are named in the scope as "ACO:ABN:<abn>", resolved through the provider data, or
+// directly as "ACO:PRV:<PRV-id>". restricted is false when the scope names no
+// organisations; an ABN with no matching provider restricts the token without
+// granting any organisation.
+func scopeOrganisations(scope string) (organisations []string, restricted bool, err error) {
+	for _, s := range strings.Fields(scope) {
+		parts := strings.SplitN(s, ":", 3)
+		if len(parts) != 3 || parts[0] != "ACO" {
+			continue
+		}
+		switch parts[1] {
+		case "ABN":
+			restricted = true
+			org, ok := provider.OrganizationForABN(parts[2])
+			if !ok {
+				log.Printf("scopeOrganisations: No provider is registered with ABN %s", parts[2])
+				continue
+			}
+			organisations = append(organisations, org)
+		case "PRV":
+			if !organizationPattern.MatchString(parts[2]) {
+				return nil, false, fmt.Errorf("invalid organisation '%s'", parts[2])
+			}
+			restricted = true
+			organisations = append(organisations, parts[2])
+		}
+	}
+	return organisations, restricted, nil
+}
+
 // registerClient handles client registration
 func registerClient(w http.ResponseWriter, r *http.Request) {
 	var req models.ClientRegistrationRequest
@@ -131,7 +196,7 @@ func registerClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create mock registration response
-	generatedClientID := "c64484a9-6cb3-4ad0-b9bd-" + time.Now().Format("150405") // Example client ID generation
+	generatedClientID := ids.UUID()
 
 	resp := models.ClientRegistrationResponse{
 		ClientName:   req.ClientName,
@@ -142,6 +207,11 @@ func registerClient(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Printf("registerClient: Response Payload: %+v", resp)
 
+	// The client secret is not sent to webhooks
+	notified := resp
+	notified.ClientSecret = ""
+	webhooks.Emit(webhooks.ClientRegistered, notified)
+
 	render.Status(r, http.StatusOK) // As per example, output is returned with 200 OK. Could be 201 Created.
 	render.JSON(w, r, resp)
 }
@@ -213,8 +283,9 @@ func deleteClient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// In a real implementation, we would delete the client from a database
-	// For this mock, we'll just return a success response
-	log.Printf("deleteClient: Processed deletion for ClientID: %s. Responding with 204 No Content.", clientID)
+	// For this mock, we'll just revoke its access tokens and return a success response
+	revoked := tokens.DeleteClient(clientID)
+	log.Printf("deleteClient: Processed deletion for ClientID: %s, revoked %d token(s). Responding with 204 No Content.", clientID, revoked)
 
 	// Return 204 No Content for successful deletion
 	w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,369 @@
+package nurses
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// csvColumns is the flattened column layout used for bulk attendance files,
+// matching test-bench/nurse_attendance_data.csv
+var csvColumns = []string{
+	"activelyRecruiting",
+	"attendanceDay_attendanceDayStatus",
+	"attendanceDay_id",
+	"attendanceDay_reportingDate",
+	"id",
+	"nominatedServiceIdentifier_system",
+	"nominatedServiceIdentifier_use",
+	"nominatedServiceIdentifier_value",
+	"nonAttendanceTime_absenceType",
+	"nonAttendanceTime_accessToClinicalDocumentation",
+	"nonAttendanceTime_accessToSupport",
+	"nonAttendanceTime_alternateArrangement",
+	"nonAttendanceTime_authorityDelegatedTo",
+	"nonAttendanceTime_id",
+	"nonAttendanceTime_unavailableEndTime",
+	"nonAttendanceTime_unavailableReason",
+	"nonAttendanceTime_unavailableStartTime",
+	"reporterDeclaration",
+	"reportingPeriod_end",
+	"reportingPeriod_start",
+	"resourceType",
+	"submissionStatus",
+	"transferHealthFacilityOther",
+	"transferHealthFacilityType",
+	"transferOption",
+	"vacancyFilled",
+	"vacancyOpenDuration",
+}
+
+// requiredCSVColumns must be present in the header of an uploaded file
+var requiredCSVColumns = []string{
+	"attendanceDay_attendanceDayStatus",
+	"attendanceDay_reportingDate",
+	"submissionStatus",
+}
+
+var timePattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]:([0-5][0-9]|60)(\.[0-9]+)?$`)
+
+// csvRow gives access to a CSV record by column name
+type csvRow struct {
+	index  map[string]int
+	record []string
+}
+
+func (row csvRow) get(column string) string {
+	i, ok := row.index[column]
+	if !ok || i >= len(row.record) {
+		return ""
+	}
+	return strings.TrimSpace(row.record[i])
+}
+
+// parseAttendanceCSV converts an uploaded attendance file into a PATCH payload.
+// Rows are grouped into attendance days by reporting date, and any row with
+// non-attendance columns adds a NonAttendanceTime to its day. Row numbers in
+// the returned issues count the header as row 1.
+func parseAttendanceCSV(r io.Reader) (models.RegisteredNurseAttendancePatchPayload, []models.Issue) {
+	var patch models.RegisteredNurseAttendancePatchPayload
+	var issues []models.Issue
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return patch, []models.Issue{csvIssue(1, "", "Could not read CSV header: "+err.Error())}
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+	for _, column := range requiredCSVColumns {
+		if _, ok := index[column]; !ok {
+			issues = append(issues, csvIssue(1, column, "Missing required column '"+column+"'"))
+		}
+	}
+	if len(issues) > 0 {
+		return patch, issues
+	}
+
+	dayIndex := make(map[string]int)
+	rowNum := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNum++
+		if err != nil {
+			issues = append(issues, csvIssue(rowNum, "", "Could not parse row: "+err.Error()))
+			continue
+		}
+		row := csvRow{index: index, record: record}
+
+		if rowNum == 2 {
+			issues = append(issues, applySubmissionColumns(&patch, row, rowNum)...)
+		} else if id := row.get("id"); id != "" && id != patch.ID {
+			issues = append(issues, csvIssue(rowNum, "id", fmt.Sprintf("id '%s' does not match '%s' from the first row", id, patch.ID)))
+		}
+
+		reportingDate := row.get("attendanceDay_reportingDate")
+		if _, err := time.Parse("2006-01-02", reportingDate); err != nil {
+			issues = append(issues, csvIssue(rowNum, "attendanceDay_reportingDate", "Invalid reportingDate '"+reportingDate+"', expected YYYY-MM-DD"))
+			continue
+		}
+		status, ok := normalizeDayStatus(row.get("attendanceDay_attendanceDayStatus"))
+		if !ok {
+			issues = append(issues, csvIssue(rowNum, "attendanceDay_attendanceDayStatus", "Invalid attendanceDayStatus '"+status+"'"))
+			continue
+		}
+
+		i, seen := dayIndex[reportingDate]
+		if !seen {
+			patch.AttendanceDays = append(patch.AttendanceDays, models.AttendanceDay{
+				ID:                  row.get("attendanceDay_id"),
+				ReportingDate:       reportingDate,
+				AttendanceDayStatus: status,
+			})
+			i = len(patch.AttendanceDays) - 1
+			dayIndex[reportingDate] = i
+		} else if patch.AttendanceDays[i].AttendanceDayStatus != status {
+			issues = append(issues, csvIssue(rowNum, "attendanceDay_attendanceDayStatus",
+				fmt.Sprintf("attendanceDayStatus '%s' conflicts with '%s' on an earlier row for %s", status, patch.AttendanceDays[i].AttendanceDayStatus, reportingDate)))
+			continue
+		}
+
+		nat, natIssues, present := parseNonAttendanceColumns(row, rowNum)
+		issues = append(issues, natIssues...)
+		if present && len(natIssues) == 0 {
+			patch.AttendanceDays[i].NonAttendanceTime = append(patch.AttendanceDays[i].NonAttendanceTime, nat)
+		}
+	}
+
+	if rowNum == 1 {
+		issues = append(issues, csvIssue(1, "", "CSV file contains no data rows"))
+	}
+
+	return patch, issues
+}
+
+// applySubmissionColumns reads the submission-level columns, which repeat on every row, from the first data row
+func applySubmissionColumns(patch *models.RegisteredNurseAttendancePatchPayload, row csvRow, rowNum int) []models.Issue {
+	var issues []models.Issue
+
+	patch.ID = row.get("id")
+	patch.ResourceType = row.get("resourceType")
+	patch.SubmissionStatus = row.get("submissionStatus")
+	patch.NominatedServiceIdentifier = models.NominatedServiceIdentifier{
+		System: row.get("nominatedServiceIdentifier_system"),
+		Use:    row.get("nominatedServiceIdentifier_use"),
+		Value:  row.get("nominatedServiceIdentifier_value"),
+	}
+	patch.ReportingPeriod = models.ReportingPeriodPatch{
+		Start: row.get("reportingPeriod_start"),
+		End:   row.get("reportingPeriod_end"),
+	}
+	patch.TransferHealthFacilityOther = row.get("transferHealthFacilityOther")
+	patch.TransferHealthFacilityType = row.get("transferHealthFacilityType")
+	patch.VacancyOpenDuration = row.get("vacancyOpenDuration")
+
+	bools := []struct {
+		column string
+		target **bool
+	}{
+		{"activelyRecruiting", &patch.ActivelyRecruiting},
+		{"reporterDeclaration", &patch.ReporterDeclaration},
+		{"transferOption", &patch.TransferOption},
+		{"vacancyFilled", &patch.VacancyFilled},
+	}
+	for _, b := range bools {
+		value, err := parseCSVBool(row.get(b.column))
+		if err != nil {
+			issues = append(issues, csvIssue(rowNum, b.column, err.Error()))
+			continue
+		}
+		*b.target = value
+	}
+
+	if patch.SubmissionStatus == "" {
+		issues = append(issues, csvIssue(rowNum, "submissionStatus", "submissionStatus is required"))
+	}
+
+	return issues
+}
+
+// parseNonAttendanceColumns reads the nonAttendanceTime_* columns of a row. present is
+// false when the row carries no non-attendance data.
+func parseNonAttendanceColumns(row csvRow, rowNum int) (nat models.NonAttendanceTime, issues []models.Issue, present bool) {
+	nat = models.NonAttendanceTime{
+		ID:                   row.get("nonAttendanceTime_id"),
+		UnavailableStartTime: row.get("nonAttendanceTime_unavailableStartTime"),
+		UnavailableEndTime:   row.get("nonAttendanceTime_unavailableEndTime"),
+		AbsenceType:          row.get("nonAttendanceTime_absenceType"),
+		AccessToSupport:      row.get("nonAttendanceTime_accessToSupport"),
+		AuthorityDelegatedTo: row.get("nonAttendanceTime_authorityDelegatedTo"),
+		AlternateArrangement: row.get("nonAttendanceTime_alternateArrangement"),
+		UnavailableReason:    row.get("nonAttendanceTime_unavailableReason"),
+	}
+	access := row.get("nonAttendanceTime_accessToClinicalDocumentation")
+
+	if nat.UnavailableStartTime == "" && nat.UnavailableEndTime == "" && nat.AbsenceType == "" &&
+		nat.AccessToSupport == "" && nat.AuthorityDelegatedTo == "" && nat.AlternateArrangement == "" &&
+		nat.UnavailableReason == "" && access == "" {
+		return nat, nil, false
+	}
+
+	if !timePattern.MatchString(nat.UnavailableStartTime) {
+		issues = append(issues, csvIssue(rowNum, "nonAttendanceTime_unavailableStartTime", "Invalid unavailableStartTime '"+nat.UnavailableStartTime+"', expected HH:MM:SS"))
+	}
+	if !timePattern.MatchString(nat.UnavailableEndTime) {
+		issues = append(issues, csvIssue(rowNum, "nonAttendanceTime_unavailableEndTime", "Invalid unavailableEndTime '"+nat.UnavailableEndTime+"', expected HH:MM:SS"))
+	}
+	if len(issues) == 0 {
+		if _, err := unavailableHours(nat); err != nil {
+			issues = append(issues, csvIssue(rowNum, "nonAttendanceTime_unavailableEndTime", err.Error()))
+		}
+	}
+
+	value, err := parseCSVBool(access)
+	if err != nil {
+		issues = append(issues, csvIssue(rowNum, "nonAttendanceTime_accessToClinicalDocumentation", err.Error()))
+	}
+	nat.AccessToClinicalDocumentation = value
+
+	return nat, issues, true
+}
+
+// writeAttendanceCSV writes submissions in the flattened column layout. Each
+// non-attendance time gets its own row; days without any get a single row.
+func writeAttendanceCSV(w io.Writer, submissions []models.RegisteredNurseAttendancePatchPayload) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+
+	for _, sub := range submissions {
+		for _, day := range sub.AttendanceDays {
+			times := day.NonAttendanceTime
+			if len(times) == 0 {
+				times = []models.NonAttendanceTime{{}}
+			}
+			for _, nat := range times {
+				values := map[string]string{
+					"activelyRecruiting":                              formatCSVBool(sub.ActivelyRecruiting),
+					"attendanceDay_attendanceDayStatus":               day.AttendanceDayStatus,
+					"attendanceDay_id":                                day.ID,
+					"attendanceDay_reportingDate":                     day.ReportingDate,
+					"id":                                              sub.ID,
+					"nominatedServiceIdentifier_system":               sub.NominatedServiceIdentifier.System,
+					"nominatedServiceIdentifier_use":                  sub.NominatedServiceIdentifier.Use,
+					"nominatedServiceIdentifier_value":                sub.NominatedServiceIdentifier.Value,
+					"nonAttendanceTime_absenceType":                   nat.AbsenceType,
+					"nonAttendanceTime_accessToClinicalDocumentation": formatCSVBool(nat.AccessToClinicalDocumentation),
+					"nonAttendanceTime_accessToSupport":               nat.AccessToSupport,
+					"nonAttendanceTime_alternateArrangement":          nat.AlternateArrangement,
+					"nonAttendanceTime_authorityDelegatedTo":          nat.AuthorityDelegatedTo,
+					"nonAttendanceTime_id":                            nat.ID,
+					"nonAttendanceTime_unavailableEndTime":            nat.UnavailableEndTime,
+					"nonAttendanceTime_unavailableReason":             nat.UnavailableReason,
+					"nonAttendanceTime_unavailableStartTime":          nat.UnavailableStartTime,
+					"reporterDeclaration":                             formatCSVBool(sub.ReporterDeclaration),
+					"reportingPeriod_end":                             sub.ReportingPeriod.End,
+					"reportingPeriod_start":                           sub.ReportingPeriod.Start,
+					"resourceType":                                    sub.ResourceType,
+					"submissionStatus":                                sub.SubmissionStatus,
+					"transferHealthFacilityOther":                     sub.TransferHealthFacilityOther,
+					"transferHealthFacilityType":                      sub.TransferHealthFacilityType,
+					"transferOption":                                  formatCSVBool(sub.TransferOption),
+					"vacancyFilled":                                   formatCSVBool(sub.VacancyFilled),
+					"vacancyOpenDuration":                             sub.VacancyOpenDuration,
+				}
+				record := make([]string, len(csvColumns))
+				for i, column := range csvColumns {
+					record[i] = values[column]
+				}
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// wantsCSV reports whether the client asked for CSV via the Accept header or _format
+func wantsCSV(r *http.Request) bool {
+	format := r.URL.Query().Get("_format")
+	if format != "" {
+		return format == "csv" || format == "text/csv"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+// renderCSV writes submissions as a CSV attachment
+func renderCSV(w http.ResponseWriter, filename string, submissions []models.RegisteredNurseAttendancePatchPayload) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	if err := writeAttendanceCSV(w, submissions); err != nil {
+		log.Printf("Error writing attendance CSV %s: %v", filename, err)
+	}
+}
+
+// formatCSVBool formats booleans the way the spreadsheet export does ("True"/"False"). Nil is empty.
+func formatCSVBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	if *b {
+		return "True"
+	}
+	return "False"
+}
+
+// parseCSVBool parses spreadsheet booleans such as "True" and "False". Empty values are nil.
+func parseCSVBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid boolean '%s'", value)
+	}
+	return &b, nil
+}
+
+// normalizeDayStatus maps an attendance day status to its canonical form, ignoring case
+func normalizeDayStatus(status string) (string, bool) {
+	for _, s := range []string{dayNotStarted, dayNurseOnSite, dayNurseNotOnSite, dayNotOperational} {
+		if strings.EqualFold(status, s) {
+			return s, true
+		}
+	}
+	return status, false
+}
+
+// csvIssue creates a row-level validation issue
+func csvIssue(rowNum int, column, text string) models.Issue {
+	issue := models.Issue{
+		Severity: "ERROR",
+		Code:     "invalid",
+		Details:  &models.IssueDetails{Text: fmt.Sprintf("Row %d: %s", rowNum, text)},
+	}
+	if column != "" {
+		issue.Expression = []string{column}
+	}
+	return issue
+}
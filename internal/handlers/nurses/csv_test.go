@@ -0,0 +1,52 @@
+package nurses
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	inProgress := newSubmission("Sub-240701-505", "SRV-00136", statusInProgress, 2024, time.July, 1)
+	inProgress.AttendanceDays[0].AttendanceDayStatus = dayNurseOnSite
+	inProgress.AttendanceDays[1].AttendanceDayStatus = dayNurseNotOnSite
+	inProgress.AttendanceDays[1].NonAttendanceTime = []models.NonAttendanceTime{{
+		ID:                   "NAT-1",
+		UnavailableStartTime: "09:00:00",
+		UnavailableEndTime:   "11:30:00",
+		UnavailableReason:    "Annual leave",
+		AccessToSupport:      "1 - Access to support",
+		AlternateArrangement: "Enrolled nurse on site",
+	}}
+
+	for _, sub := range []models.RegisteredNurseAttendancePatchPayload{
+		newSubmission("Sub-240801-506", "SRV-00136", statusNotStarted, 2024, time.August, 1),
+		inProgress,
+	} {
+		t.Run(sub.ID, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeAttendanceCSV(&buf, []models.RegisteredNurseAttendancePatchPayload{sub}); err != nil {
+				t.Fatal(err)
+			}
+			patch, issues := parseAttendanceCSV(&buf)
+			if len(issues) > 0 {
+				t.Fatalf("exported CSV has issues: %+v", issues)
+			}
+
+			// Uploading the export unchanged keeps the status, which must be accepted
+			if err := validateTransition(sub.SubmissionStatus, patch.SubmissionStatus, patch.ReporterDeclaration); err != nil {
+				t.Fatalf("re-uploading the export was rejected: %v", err)
+			}
+			got := copySubmission(sub)
+			if err := applyPatch(&got, patch); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, sub) {
+				t.Errorf("round trip changed the submission\n got %+v\nwant %+v", got, sub)
+			}
+		})
+	}
+}
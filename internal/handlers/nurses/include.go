@@ -0,0 +1,79 @@
+package nurses
+
+import (
+	"net/http"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/search"
+)
+
+// Register the references from RN attendance submissions to healthcare services
+// and providers for _include and _revinclude
+func init() {
+	// RegisteredNurseAttendance.nominatedServiceIdentifier -> HealthcareService
+	search.RegisterInclude("RegisteredNurseAttendance", "RegisteredNurseAttendance:service", includeService)
+
+	// HealthcareService <- RegisteredNurseAttendance.nominatedServiceIdentifier
+	search.RegisterRevInclude("HealthcareService", "RegisteredNurseAttendance:service", revIncludeServiceSubmissions)
+
+	// Provider <- RegisteredNurseAttendance, through the services the provider offers
+	search.RegisterRevInclude("Provider", "RegisteredNurseAttendance:organization", revIncludeOrganizationSubmissions)
+}
+
+// includeService returns the healthcare service a submission was made for
+func includeService(r *http.Request, resource any) []search.Linked {
+	submission, ok := resource.(models.RegisteredNurseAttendancePatchPayload)
+	if !ok || !serviceAllowed(r, submission.NominatedServiceIdentifier.Value) {
+		return nil
+	}
+
+	service, ok := provider.HealthcareServiceForIntegrationID(submission.NominatedServiceIdentifier.Value)
+	if !ok {
+		return nil
+	}
+	return []search.Linked{{Endpoint: "HealthcareService", ID: service.ID, Resource: service}}
+}
+
+// revIncludeServiceSubmissions returns the submissions made for a healthcare service
+func revIncludeServiceSubmissions(r *http.Request, resource any) []search.Linked {
+	service, ok := resource.(models.HealthcareService)
+	if !ok {
+		return nil
+	}
+
+	var services []string
+	for _, identifier := range service.Identifier {
+		if identifier.System == provider.IntegrationIDSystem {
+			services = append(services, identifier.Value)
+		}
+	}
+	return linkedSubmissions(r, services)
+}
+
+// revIncludeOrganizationSubmissions returns the submissions made for the services of a provider
+func revIncludeOrganizationSubmissions(r *http.Request, resource any) []search.Linked {
+	organization, ok := resource.(models.Provider)
+	if !ok {
+		return nil
+	}
+	return linkedSubmissions(r, provider.ServiceIntegrationIDs(organization.ID))
+}
+
+// linkedSubmissions returns the submissions for the given service integration IDs
+func linkedSubmissions(r *http.Request, services []string) []search.Linked {
+	if len(services) == 0 {
+		return nil
+	}
+
+	submissionsMu.Lock()
+	defer submissionsMu.Unlock()
+
+	var linked []search.Linked
+	for _, submission := range filterSubmissions(services, "") {
+		if serviceAllowed(r, submission.NominatedServiceIdentifier.Value) {
+			linked = append(linked, search.Linked{Endpoint: "RegisteredNurseAttendance", ID: submission.ID, Resource: submission})
+		}
+	}
+	return linked
+}
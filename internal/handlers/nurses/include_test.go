@@ -0,0 +1,87 @@
+package nurses
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/idgen"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// searchBundle runs a search against the provider and nurses handlers
+func searchBundle(t *testing.T, target string) (int, models.Bundle, models.OperationOutcome) {
+	t.Helper()
+	r := chi.NewRouter()
+	provider.RegisterHandlers(r)
+	RegisterHandlers(r, clock.NewVirtual(), idgen.New())
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+
+	var bundle models.Bundle
+	var outcome models.OperationOutcome
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &bundle); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		json.Unmarshal(rec.Body.Bytes(), &outcome)
+	}
+	return rec.Code, bundle, outcome
+}
+
+// entryModes counts the bundle entries by search mode, failing on repeated entries
+func entryModes(t *testing.T, bundle models.Bundle) map[string]int {
+	t.Helper()
+	modes := map[string]int{}
+	seen := map[string]bool{}
+	for _, entry := range bundle.Entry {
+		if seen[entry.FullURL] {
+			t.Errorf("%s is in the bundle twice", entry.FullURL)
+		}
+		seen[entry.FullURL] = true
+		modes[entry.Search.Mode]++
+	}
+	return modes
+}
+
+func TestIncludeServiceOnce(t *testing.T) {
+	// Two SRV-00136 submissions reference the same healthcare service
+	code, bundle, _ := searchBundle(t, "/RegisteredNurseAttendance?service=SRV-00136&_include=RegisteredNurseAttendance:service")
+	if code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if modes := entryModes(t, bundle); modes["match"] != 2 || modes["include"] != 1 {
+		t.Errorf("got %v entries, want 2 matches and 1 include", modes)
+	}
+}
+
+func TestRevIncludeThroughTwoNames(t *testing.T) {
+	// HealthcareService:organization and :providedBy reach the same services
+	code, bundle, _ := searchBundle(t, "/Provider?_revinclude=HealthcareService:organization&_revinclude=HealthcareService:providedBy&_revinclude=RegisteredNurseAttendance:organization")
+	if code != http.StatusOK {
+		t.Fatalf("got status %d", code)
+	}
+	if modes := entryModes(t, bundle); modes["match"] == 0 || modes["include"] == 0 {
+		t.Errorf("got %v entries, want matches and includes", modes)
+	}
+}
+
+func TestUnknownInclude(t *testing.T) {
+	for _, target := range []string{
+		"/RegisteredNurseAttendance?_include=RegisteredNurseAttendance:organization",
+		"/RegisteredNurseAttendance?_revinclude=RegisteredNurseAttendance:service",
+		"/Provider?_include=HealthcareService:providedBy",
+		"/HealthcareService?_revinclude=RegisteredNurseAttendance:unknown",
+	} {
+		code, _, outcome := searchBundle(t, target)
+		if code != http.StatusBadRequest || len(outcome.Issue) == 0 || outcome.Issue[0].Code != "not-supported" {
+			t.Errorf("%s: got status %d and %+v, want a 400 not-supported OperationOutcome", target, code, outcome.Issue)
+		}
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"log" // Added for logging
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/idgen"
 	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/search"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
+	"github.com/jasonchiu/dohac-mock-apis/internal/webhooks"
 )
 
 // Mock data for nurse attendances
@@ -128,8 +132,17 @@ var mockAttendances = []models.RegisteredNurseAttendance{
 	},
 }
 
+// clk timestamps processed CSV uploads and ids generates attendance day and
+// non-attendance time IDs
+var (
+	clk = clock.System
+	ids = idgen.New()
+)
+
 // RegisterHandlers registers the registered nurses handlers
-func RegisterHandlers(r chi.Router) {
+func RegisterHandlers(r chi.Router, c clock.Clock, g *idgen.Generator) {
+	clk, ids = c, g
+
 	r.Route("/RegisteredNurseAttendance", func(r chi.Router) {
 		r.Get("/", getAttendances)
 		r.Route("/{id}", func(r chi.Router) {
@@ -139,98 +152,95 @@ func RegisterHandlers(r chi.Router) {
 	})
 }
 
-// getAttendances returns all registered nurse attendances
+// getAttendances returns a searchset bundle of monthly RN attendance submissions, or their summaries
 func getAttendances(w http.ResponseWriter, r *http.Request) {
 	// Optional filters
-	// organization := r.URL.Query().Get("organization")
+	organization := r.URL.Query().Get("organization")
 	service := r.URL.Query().Get("service")
-	// reportingPeriod := r.URL.Query().Get("reporting-period")
+	reportingPeriod := r.URL.Query().Get("reporting-period")
 	summary := r.URL.Query().Get("summary")
-	countStr := r.URL.Query().Get("_count")
-	pageStr := r.URL.Query().Get("page")
+	submittedBy := r.URL.Query().Get("submitted-by")
+	modifiedBy := r.URL.Query().Get("modified-by")
+
+	// Validate filters against the OAS query parameter patterns
+	if err := validateSearchParams(r); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
 
 	// Parse pagination parameters
-	count := 10
-	page := 1
-	if countStr != "" {
-		var err error
-		count, err = strconv.Atoi(countStr)
-		if err != nil || count <= 0 {
-			count = 10
-		}
+	paging, err := search.ParsePaging(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	if organization != "" && !tokens.AllowsOrganization(r.Context(), organization) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
 	}
-	if pageStr != "" {
-		var err error
-		page, err = strconv.Atoi(pageStr)
-		if err != nil || page <= 0 {
-			page = 1
+
+	// Resolve the organization to the services it provides
+	services := resolveServiceFilter(service, organization)
+
+	submissionsMu.Lock()
+	var matches []models.RegisteredNurseAttendancePatchPayload
+	for _, submission := range filterSubmissions(services, reportingPeriod) {
+		if submittedBy != "" && !strings.EqualFold(submission.SubmittedBy, submittedBy) {
+			continue
+		}
+		if modifiedBy != "" && !strings.EqualFold(submission.LastModifiedBy, modifiedBy) {
+			continue
+		}
+		// Only return submissions for organisations the caller's token is bound to
+		if serviceAllowed(r, submission.NominatedServiceIdentifier.Value) {
+			matches = append(matches, submission)
 		}
 	}
+	submissionsMu.Unlock()
 
 	if summary == "true" {
-		// Return summary data
-		summaries := []models.AttendanceSummary{
-			{
-				ID:             "SUM-12345",
-				ServiceID:      "SVC-54321",
-				ServiceName:    "Sunset Residential Care",
-				Date:           time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
-				TotalHours:     16.0,
-				DailyRequired:  true,
-				CompliantDay:   true,
-				CompliantWeek:  true,
-				CompliantMonth: true,
-			},
-			{
-				ID:             "SUM-23456",
-				ServiceID:      "SVC-24680",
-				ServiceName:    "Golden Years Residential Care",
-				Date:           time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC),
-				TotalHours:     8.0,
-				DailyRequired:  true,
-				CompliantDay:   true,
-				CompliantWeek:  true,
-				CompliantMonth: true,
-			},
+		// Compute summaries from the stored submissions
+		summaries := []models.AttendanceSummary{}
+		for _, submission := range matches {
+			s, err := summarizeSubmission(submission)
+			if err != nil {
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Submission "+submission.ID+": "+err.Error()))
+				return
+			}
+			summaries = append(summaries, s)
 		}
-		render.JSON(w, r, summaries)
+		search.SetLinkHeader(w, search.Links(r, len(summaries), paging))
+		render.JSON(w, r, search.Paginate(summaries, paging))
 		return
 	}
 
-	// Filter attendances by service
-	var filteredAttendances []models.RegisteredNurseAttendance
-	if service != "" {
-		for _, attendance := range mockAttendances {
-			if attendance.Subject.Reference == "HealthcareService/"+service {
-				filteredAttendances = append(filteredAttendances, attendance)
-			}
-		}
-	} else {
-		filteredAttendances = mockAttendances
+	if wantsCSV(r) {
+		// Export submissions in the same layout accepted by the CSV PATCH
+		renderCSV(w, "RegisteredNurseAttendance.csv", matches)
+		return
 	}
 
-	// Create bundle of attendances
-	bundle := models.Bundle{
-		ResourceType: "Bundle",
-		ID:           "bundle-rn-attendances",
-		Type:         "searchset",
-		Total:        len(filteredAttendances),
-		Link: []models.BundleLink{
-			{
-				Relation: "self",
-				URL:      "https://api.health.gov.au/RegisteredNurseAttendance",
-			},
-		},
+	// Create bundle of submissions for the requested page
+	bundle := search.NewBundle(r, "bundle-rn-attendances", len(matches), paging)
+	var page []any
+	for _, submission := range search.Paginate(matches, paging) {
+		search.AddMatch(&bundle, search.FullURL(r, submission.ID), submission)
+		page = append(page, submission)
 	}
 
-	// Add entries to bundle
-	for _, attendance := range filteredAttendances {
-		bundle.Entry = append(bundle.Entry, models.BundleEntry{
-			FullURL:  "https://api.health.gov.au/RegisteredNurseAttendance/" + attendance.ID,
-			Resource: attendance,
-		})
+	// Add the resources requested by _include and _revinclude
+	if err := search.AddIncludes(r, "RegisteredNurseAttendance", &bundle, page); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-supported", err.Error()))
+		return
 	}
 
+	search.SetLinkHeader(w, bundle.Link)
 	render.JSON(w, r, bundle)
 }
 
@@ -238,9 +248,41 @@ func getAttendances(w http.ResponseWriter, r *http.Request) {
 func getAttendanceByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	// Monthly submissions take precedence over the legacy encounter records
+	submissionsMu.Lock()
+	submission := findSubmission(id)
+	if submission != nil {
+		found := copySubmission(*submission)
+		submissionsMu.Unlock()
+		if !serviceAllowed(r, found.NominatedServiceIdentifier.Value) {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+			return
+		}
+		if wantsCSV(r) {
+			renderCSV(w, id+".csv", []models.RegisteredNurseAttendancePatchPayload{found})
+			return
+		}
+		render.JSON(w, r, found)
+		return
+	}
+	submissionsMu.Unlock()
+
+	// Legacy encounter records have no CSV representation
+	if wantsCSV(r) {
+		render.Status(r, http.StatusNotAcceptable)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-supported", "CSV is only available for submission records"))
+		return
+	}
+
 	// Find attendance by ID
 	for _, attendance := range mockAttendances {
 		if attendance.ID == id {
+			if !serviceAllowed(r, strings.TrimPrefix(attendance.Subject.Reference, "HealthcareService/")) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+				return
+			}
 			render.JSON(w, r, attendance)
 			return
 		}
@@ -256,41 +298,40 @@ func updateAttendance(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	contentType := r.Header.Get("Content-Type")
 
-	// Handle CSV PATCH for submission IDs (e.g., "Sub-123-456") separately
-	// because they don't correspond to an existing record in our mock data.
+	// Handle CSV PATCH for submission IDs (e.g., "Sub-123-456"): the file is parsed
+	// into attendance days and applied to the submission like a JSON PATCH.
 	if strings.Contains(contentType, "multipart/form-data") && strings.HasPrefix(id, "Sub-") {
 		if err := r.ParseMultipartForm(10 << 20); err != nil {
 			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, map[string]string{"error": "Could not parse multipart form: " + err.Error()})
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Could not parse multipart form: "+err.Error()))
 			return
 		}
 
 		file, handler, err := r.FormFile("csv")
 		if err != nil {
 			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, map[string]string{"error": "Could not retrieve CSV file: " + err.Error()})
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Could not retrieve CSV file: "+err.Error()))
 			return
 		}
 		defer file.Close()
 
 		log.Printf("Received CSV file for submission: %s, Size: %d bytes for ID: %s", handler.Filename, handler.Size, id)
 
-		// Mock a successful response since we are not updating a real record.
-		mockResponse := models.RegisteredNurseAttendance{
-			ResourceType: "Encounter",
-			ID:           id,
-			Status:       "completed",
-			Subject: models.Reference{
-				Reference: "HealthcareService/SVC-54321",
-				Display:   "Sunset Residential Care",
-			},
-			Note: []models.Annotation{
-				{
-					Text: fmt.Sprintf("CSV file '%s' processed for submission %s at %s.", handler.Filename, id, time.Now().Format(time.RFC3339)),
-				},
-			},
+		patch, issues := parseAttendanceCSV(file)
+		if len(issues) > 0 {
+			log.Printf("Rejected CSV file %s for submission %s with %d issue(s)", handler.Filename, id, len(issues))
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, models.OperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+			return
 		}
-		render.JSON(w, r, mockResponse)
+
+		patchSubmission(w, r, id, patch)
+		return
+	}
+
+	// JSON PATCH against a monthly submission drives the submission status workflow
+	if strings.Contains(contentType, "application/json") && strings.HasPrefix(id, "Sub-") {
+		updateSubmission(w, r, id)
 		return
 	}
 
@@ -310,6 +351,12 @@ func updateAttendance(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !serviceAllowed(r, strings.TrimPrefix(attendanceToUpdate.Subject.Reference, "HealthcareService/")) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
+	}
+
 	// Now, handle the specific content type.
 	if strings.Contains(contentType, "application/json") {
 		// Handle JSON PATCH
@@ -342,7 +389,7 @@ func updateAttendance(w http.ResponseWriter, r *http.Request) {
 
 		log.Printf("Received CSV file: %s, Size: %d bytes for ID: %s", handler.Filename, handler.Size, id)
 		attendanceToUpdate.Note = append(attendanceToUpdate.Note, models.Annotation{
-			Text: fmt.Sprintf("CSV file '%s' processed at %s.", handler.Filename, time.Now().Format(time.RFC3339)),
+			Text: fmt.Sprintf("CSV file '%s' processed at %s.", handler.Filename, clk.Now().Format(time.RFC3339)),
 		})
 		log.Printf("Updated note via CSV for attendance record ID: %s", id)
 		render.JSON(w, r, attendanceToUpdate)
@@ -351,4 +398,80 @@ func updateAttendance(w http.ResponseWriter, r *http.Request) {
 		render.Status(r, http.StatusUnsupportedMediaType)
 		render.JSON(w, r, map[string]string{"error": "Unsupported Content-Type: " + contentType + ". Must be 'application/json' or 'multipart/form-data'."})
 	}
-}
\ No newline at end of file
+}
+
+// updateSubmission applies a JSON PATCH to a monthly submission, enforcing the
+// Not Started -> In Progress -> Submitted workflow.
+func updateSubmission(w http.ResponseWriter, r *http.Request, id string) {
+	var patch models.RegisteredNurseAttendancePatchPayload
+	if err := render.DecodeJSON(r.Body, &patch); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid JSON payload: "+err.Error()))
+		return
+	}
+
+	if patch.SubmissionStatus == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "required", "submissionStatus is required"))
+		return
+	}
+
+	patchSubmission(w, r, id, patch)
+}
+
+// patchSubmission validates the status transition and merges a decoded PATCH into the stored submission
+func patchSubmission(w http.ResponseWriter, r *http.Request, id string, patch models.RegisteredNurseAttendancePatchPayload) {
+	submissionsMu.Lock()
+	defer submissionsMu.Unlock()
+
+	submission := findSubmission(id)
+	if submission == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Registered nurse attendance not found"))
+		return
+	}
+
+	if !serviceAllowed(r, submission.NominatedServiceIdentifier.Value) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
+	}
+
+	if patch.ID != "" && patch.ID != id {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Payload id does not match the URL id"))
+		return
+	}
+
+	if err := validateTransition(submission.SubmissionStatus, patch.SubmissionStatus, patch.ReporterDeclaration); err != nil {
+		log.Printf("Rejected PATCH for submission %s: %v", id, err)
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "business-rule", err.Error()))
+		return
+	}
+
+	previousStatus := submission.SubmissionStatus
+	if err := applyPatch(submission, patch); err != nil {
+		log.Printf("Rejected PATCH for submission %s: %v", id, err)
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	// Attribute the change, and the declaration when submitting, to the end user
+	if user := r.Header.Get("X-Federated-Id"); user != "" {
+		submission.LastModifiedBy = user
+		if normalizeStatus(submission.SubmissionStatus) == statusSubmitted && normalizeStatus(previousStatus) != statusSubmitted {
+			submission.SubmittedBy = user
+		}
+	}
+
+	log.Printf("Updated submission %s, status now %s", id, submission.SubmissionStatus)
+	if submission.SubmissionStatus != previousStatus {
+		webhooks.Emit(webhooks.SubmissionStatusChanged, models.SubmissionStatusChange{
+			PreviousStatus: previousStatus,
+			Submission:     *submission,
+		})
+	}
+	render.JSON(w, r, submission)
+}
@@ -0,0 +1,339 @@
+package nurses
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
+)
+
+// Submission statuses used by the 24/7 RN reporting workflow
+const (
+	statusNotStarted = "Not Started"
+	statusInProgress = "In Progress"
+	statusSubmitted  = "Submitted"
+)
+
+// Attendance day statuses
+const (
+	dayNotStarted     = "Not Started"
+	dayNurseOnSite    = "Nurse On Site"
+	dayNurseNotOnSite = "Nurse not on site"
+	dayNotOperational = "Service was not operational on this day"
+)
+
+// allowedTransitions lists the statuses a submission may move to from its current status
+var allowedTransitions = map[string][]string{
+	statusNotStarted: {statusInProgress},
+	statusInProgress: {statusInProgress, statusSubmitted},
+	statusSubmitted:  {},
+}
+
+// submissionsMu guards mockSubmissions
+var submissionsMu sync.Mutex
+
+// Mock data for monthly 24/7 RN submissions
+var mockSubmissions = []models.RegisteredNurseAttendancePatchPayload{
+	newSubmission("Sub-240708-504", "SRV-00136", statusInProgress, 2024, time.July, 15403),
+	newSubmission("Sub-240605-505", "SRV-00137", statusInProgress, 2024, time.June, 15434),
+	newSubmission("Sub-240801-506", "SRV-00136", statusNotStarted, 2024, time.August, 15464),
+}
+
+func init() {
+	// Seed some attendance against the July submission to match the spec example
+	july := &mockSubmissions[0]
+	july.AttendanceDays[0].AttendanceDayStatus = dayNurseNotOnSite
+	july.AttendanceDays[0].NonAttendanceTime = []models.NonAttendanceTime{
+		{
+			ID:                            "RNU-3583",
+			UnavailableStartTime:          "09:00:00",
+			UnavailableEndTime:            "10:30:00",
+			AbsenceType:                   "Planned",
+			AuthorityDelegatedTo:          "PCW or AIN",
+			AccessToSupport:               "4 - GP on-call who can attend in person",
+			AccessToClinicalDocumentation: boolPtr(true),
+		},
+	}
+	june := &mockSubmissions[1]
+	june.AttendanceDays[0].AttendanceDayStatus = dayNurseOnSite
+	june.AttendanceDays[1].AttendanceDayStatus = dayNurseOnSite
+}
+
+// newSubmission builds a submission with one "Not Started" attendance day for every day of the month
+func newSubmission(id, serviceID, status string, year int, month time.Month, firstDaySeq int) models.RegisteredNurseAttendancePatchPayload {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+
+	var days []models.AttendanceDay
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, models.AttendanceDay{
+			ID:                  fmt.Sprintf("SD-%s-%d", strings.Split(id, "-")[1], firstDaySeq+len(days)),
+			ReportingDate:       d.Format("2006-01-02"),
+			AttendanceDayStatus: dayNotStarted,
+		})
+	}
+
+	return models.RegisteredNurseAttendancePatchPayload{
+		ResourceType: "RegisteredNurseAttendance",
+		ID:           id,
+		NominatedServiceIdentifier: models.NominatedServiceIdentifier{
+			System: "https://api.health.gov.au/integrationID",
+			Use:    "official",
+			Value:  serviceID,
+		},
+		SubmissionStatus: status,
+		ReportingPeriod: models.ReportingPeriodPatch{
+			Start: start.Format("2006-01-02"),
+			End:   end.Format("2006-01-02"),
+		},
+		AttendanceDays: days,
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// hasNonAttendance reports whether a day has a non-attendance time with an ID
+func hasNonAttendance(day models.AttendanceDay, id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, nat := range day.NonAttendanceTime {
+		if nat.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// copySubmission returns a copy of a submission that shares no attendance day
+// or non-attendance time slices with it, so it can be used after submissionsMu
+// is released
+func copySubmission(sub models.RegisteredNurseAttendancePatchPayload) models.RegisteredNurseAttendancePatchPayload {
+	c := sub
+	if sub.AttendanceDays != nil {
+		c.AttendanceDays = make([]models.AttendanceDay, len(sub.AttendanceDays))
+		for i, day := range sub.AttendanceDays {
+			day.NonAttendanceTime = append([]models.NonAttendanceTime(nil), day.NonAttendanceTime...)
+			c.AttendanceDays[i] = day
+		}
+	}
+	return c
+}
+
+// findSubmission returns a pointer to the stored submission with the given ID, or nil.
+// Callers must hold submissionsMu.
+func findSubmission(id string) *models.RegisteredNurseAttendancePatchPayload {
+	for i := range mockSubmissions {
+		if mockSubmissions[i].ID == id {
+			return &mockSubmissions[i]
+		}
+	}
+	return nil
+}
+
+// Query parameter patterns from the Registered Nurses OAS
+var searchParamPatterns = map[string]*regexp.Regexp{
+	"organization":     regexp.MustCompile(`^PRV-\d+$`),
+	"service":          regexp.MustCompile(`^SRV-\d+$`),
+	"reporting-period": regexp.MustCompile(`^(19|20)\d{2}\-(0[1-9]|1[012])$`),
+	"summary":          regexp.MustCompile(`^(true|false)$`),
+	// Filters on the X-Federated-Id of the end users who changed the submissions
+	"submitted-by": regexp.MustCompile(`^\S+@\S+\.\S+$`),
+	"modified-by":  regexp.MustCompile(`^\S+@\S+\.\S+$`),
+}
+
+// validateSearchParams checks the search filters against their OAS patterns
+func validateSearchParams(r *http.Request) error {
+	for _, name := range []string{"organization", "service", "reporting-period", "summary", "submitted-by", "modified-by"} {
+		value := r.URL.Query().Get(name)
+		if value != "" && !searchParamPatterns[name].MatchString(value) {
+			return fmt.Errorf("invalid %s '%s', must match %s", name, value, searchParamPatterns[name].String())
+		}
+	}
+	return nil
+}
+
+// resolveServiceFilter combines the service and organization filters into the
+// set of service IDs to match. A nil result means no service restriction.
+func resolveServiceFilter(service, organization string) []string {
+	if organization == "" {
+		if service == "" {
+			return nil
+		}
+		return []string{service}
+	}
+
+	orgServices := provider.ServiceIntegrationIDs(organization)
+	if service == "" {
+		return orgServices
+	}
+	for _, id := range orgServices {
+		if id == service {
+			return []string{service}
+		}
+	}
+	return []string{}
+}
+
+// serviceAllowed reports whether the caller's token grants access to the
+// organisation providing a service (SRV- or SVC- ID)
+func serviceAllowed(r *http.Request, serviceID string) bool {
+	org, _ := provider.OrganizationForService(serviceID)
+	return tokens.AllowsOrganization(r.Context(), org)
+}
+
+// filterSubmissions returns copies of the submissions for a set of service IDs
+// and a YYYY-MM reporting period. A nil services slice and an empty period match
+// everything. Callers must hold submissionsMu.
+func filterSubmissions(services []string, reportingPeriod string) []models.RegisteredNurseAttendancePatchPayload {
+	matches := []models.RegisteredNurseAttendancePatchPayload{}
+	for _, sub := range mockSubmissions {
+		if services != nil && !containsString(services, sub.NominatedServiceIdentifier.Value) {
+			continue
+		}
+		if reportingPeriod != "" && !strings.HasPrefix(sub.ReportingPeriod.Start, reportingPeriod) {
+			continue
+		}
+		matches = append(matches, copySubmission(sub))
+	}
+	return matches
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeStatus maps a submission status to its canonical form, ignoring case
+// ("In progress" and "In Progress" both appear in the spec). Unknown statuses are returned as-is.
+func normalizeStatus(status string) string {
+	for _, s := range []string{statusNotStarted, statusInProgress, statusSubmitted} {
+		if strings.EqualFold(strings.TrimSpace(status), s) {
+			return s
+		}
+	}
+	return status
+}
+
+// validateTransition checks that a submission can move from one status to another
+func validateTransition(from, to string, declaration *bool) error {
+	from = normalizeStatus(from)
+	to = normalizeStatus(to)
+
+	if from == statusSubmitted {
+		return fmt.Errorf("submission has already been submitted and can no longer be edited")
+	}
+
+	// Keeping the current status is a no-op, e.g. re-uploading an exported CSV
+	if from == to {
+		return nil
+	}
+
+	allowed := false
+	for _, s := range allowedTransitions[from] {
+		if s == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("invalid submissionStatus transition from '%s' to '%s'", from, to)
+	}
+
+	if to == statusSubmitted && (declaration == nil || !*declaration) {
+		return fmt.Errorf("reporterDeclaration must be true to submit")
+	}
+
+	return nil
+}
+
+// applyPatch merges a PATCH payload into a stored submission. Attendance days are
+// matched on reportingDate; a matched day has its non-attendance times replaced.
+func applyPatch(sub *models.RegisteredNurseAttendancePatchPayload, patch models.RegisteredNurseAttendancePatchPayload) error {
+	// Validate everything before changing the submission, so that stored data
+	// can always be summarised
+	for i, day := range patch.AttendanceDays {
+		if _, err := time.Parse("2006-01-02", day.ReportingDate); err != nil {
+			return fmt.Errorf("invalid attendance day reportingDate '%s', must be YYYY-MM-DD", day.ReportingDate)
+		}
+		if day.ReportingDate < sub.ReportingPeriod.Start || day.ReportingDate > sub.ReportingPeriod.End {
+			return fmt.Errorf("attendance day %s is outside the reporting period %s to %s",
+				day.ReportingDate, sub.ReportingPeriod.Start, sub.ReportingPeriod.End)
+		}
+		status, ok := normalizeDayStatus(day.AttendanceDayStatus)
+		if !ok {
+			return fmt.Errorf("attendance day %s: invalid attendanceDayStatus '%s'", day.ReportingDate, day.AttendanceDayStatus)
+		}
+		patch.AttendanceDays[i].AttendanceDayStatus = status
+		for _, nat := range day.NonAttendanceTime {
+			if _, err := unavailableHours(nat); err != nil {
+				return fmt.Errorf("attendance day %s: %w", day.ReportingDate, err)
+			}
+		}
+	}
+
+	for _, day := range patch.AttendanceDays {
+		existing := -1
+		for i := range sub.AttendanceDays {
+			if sub.AttendanceDays[i].ReportingDate == day.ReportingDate {
+				existing = i
+				break
+			}
+		}
+
+		// Non-attendance times keep the IDs they were exported with; new ones get fresh IDs
+		for i := range day.NonAttendanceTime {
+			if existing < 0 || !hasNonAttendance(sub.AttendanceDays[existing], day.NonAttendanceTime[i].ID) {
+				day.NonAttendanceTime[i].ID = ids.NonAttendance()
+			}
+		}
+
+		if existing >= 0 {
+			sub.AttendanceDays[existing].AttendanceDayStatus = day.AttendanceDayStatus
+			sub.AttendanceDays[existing].NonAttendanceTime = day.NonAttendanceTime
+			continue
+		}
+
+		if day.ID == "" {
+			day.ID = ids.SubmissionDay(sub.ID)
+		}
+		sub.AttendanceDays = append(sub.AttendanceDays, day)
+	}
+
+	if patch.ActivelyRecruiting != nil {
+		sub.ActivelyRecruiting = patch.ActivelyRecruiting
+	}
+	if patch.ReporterDeclaration != nil {
+		sub.ReporterDeclaration = patch.ReporterDeclaration
+	}
+	if patch.TransferOption != nil {
+		sub.TransferOption = patch.TransferOption
+	}
+	if patch.VacancyFilled != nil {
+		sub.VacancyFilled = patch.VacancyFilled
+	}
+	if patch.TransferHealthFacilityType != "" {
+		sub.TransferHealthFacilityType = patch.TransferHealthFacilityType
+	}
+	if patch.TransferHealthFacilityOther != "" {
+		sub.TransferHealthFacilityOther = patch.TransferHealthFacilityOther
+	}
+	if patch.VacancyOpenDuration != "" {
+		sub.VacancyOpenDuration = patch.VacancyOpenDuration
+	}
+	sub.SubmissionStatus = normalizeStatus(patch.SubmissionStatus)
+
+	return nil
+}
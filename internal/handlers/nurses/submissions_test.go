@@ -0,0 +1,55 @@
+package nurses
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+func TestValidateTransition(t *testing.T) {
+	yes, no := boolPtr(true), boolPtr(false)
+	tests := []struct {
+		name        string
+		from, to    string
+		declaration *bool
+		ok          bool
+	}{
+		{"start reporting", statusNotStarted, statusInProgress, nil, true},
+		{"keep not started", statusNotStarted, statusNotStarted, nil, true},
+		{"keep in progress", statusInProgress, statusInProgress, nil, true},
+		{"submit with declaration", statusInProgress, statusSubmitted, yes, true},
+		{"status case is ignored", "in progress", "submitted", yes, true},
+		{"submit without starting", statusNotStarted, statusSubmitted, yes, false},
+		{"submit without declaration", statusInProgress, statusSubmitted, nil, false},
+		{"submit with false declaration", statusInProgress, statusSubmitted, no, false},
+		{"reopen submitted", statusSubmitted, statusInProgress, nil, false},
+		{"resubmit submitted", statusSubmitted, statusSubmitted, yes, false},
+		{"back to not started", statusInProgress, statusNotStarted, nil, false},
+		{"unknown status", statusInProgress, "Done", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTransition(tt.from, tt.to, tt.declaration)
+			if (err == nil) != tt.ok {
+				t.Errorf("validateTransition(%q, %q) = %v, want ok %v", tt.from, tt.to, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestApplyPatchRejectsMalformedReportingDate(t *testing.T) {
+	sub := newSubmission("Sub-240708-504", "SRV-00136", statusInProgress, 2024, time.July, 1)
+	for _, date := range []string{"2024-07-1x", "2024-7-01", "2024-07-32", ""} {
+		patch := models.RegisteredNurseAttendancePatchPayload{
+			AttendanceDays: []models.AttendanceDay{{ReportingDate: date, AttendanceDayStatus: dayNurseOnSite}},
+		}
+		got := copySubmission(sub)
+		if err := applyPatch(&got, patch); err == nil {
+			t.Errorf("reportingDate %q was accepted", date)
+		}
+		if len(got.AttendanceDays) != len(sub.AttendanceDays) {
+			t.Errorf("reportingDate %q added a day", date)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package nurses
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// hoursPerDay is the RN coverage required for each operational day
+const hoursPerDay = 24.0
+
+// summarizeSubmission computes the attendance summary for a submission from its
+// attendance days and non-attendance times. Days still "Not Started" have not
+// been reported, so they add no coverage hours and no day or week is compliant
+// until they are.
+//
+// Compliance flags:
+//   - CompliantDay: every day has been reported, and every operational day had
+//     an RN on site for the full 24 hours
+//   - CompliantWeek: every ISO week is fully reported with no unavailable hours
+//     lacking an alternate arrangement; Weeks holds the result for each week
+//   - CompliantMonth: every week of the period is compliant
+func summarizeSubmission(sub models.RegisteredNurseAttendancePatchPayload) (models.AttendanceSummary, error) {
+	summary := models.AttendanceSummary{
+		ResourceType:               "RegisteredNurseAttendance",
+		ID:                         sub.ID,
+		NominatedServiceIdentifier: sub.NominatedServiceIdentifier,
+		SubmissionStatus:           sub.SubmissionStatus,
+		ReportingPeriod:            sub.ReportingPeriod,
+		CompliantDay:               len(sub.AttendanceDays) > 0,
+	}
+
+	// Days are grouped into ISO weeks in date order
+	days := append([]models.AttendanceDay(nil), sub.AttendanceDays...)
+	sort.Slice(days, func(i, j int) bool { return days[i].ReportingDate < days[j].ReportingDate })
+
+	var week *models.WeekSummary
+	var weekID isoWeek
+	for _, day := range days {
+		date, err := time.Parse("2006-01-02", day.ReportingDate)
+		if err != nil {
+			return summary, fmt.Errorf("invalid reportingDate '%s'", day.ReportingDate)
+		}
+		if week == nil || weekOf(date) != weekID {
+			summary.Weeks = append(summary.Weeks, models.WeekSummary{Start: day.ReportingDate, Reported: true})
+			week, weekID = &summary.Weeks[len(summary.Weeks)-1], weekOf(date)
+		}
+		week.End = day.ReportingDate
+
+		if day.AttendanceDayStatus == dayNotStarted {
+			summary.CompliantDay = false
+			week.Reported = false
+			continue
+		}
+		if day.AttendanceDayStatus == dayNotOperational {
+			continue
+		}
+		summary.TotalCoverageHours += hoursPerDay
+
+		var dayUnavailable float64
+		for _, nat := range day.NonAttendanceTime {
+			hours, err := unavailableHours(nat)
+			if err != nil {
+				return summary, fmt.Errorf("attendance day %s: %w", day.ReportingDate, err)
+			}
+			dayUnavailable += hours
+			if !hasAlternateArrangement(nat) {
+				summary.TotalHoursWithoutAltArrangement += hours
+				week.TotalHoursWithoutAltArrangement += hours
+			}
+		}
+		if dayUnavailable > 0 {
+			summary.CompliantDay = false
+		}
+		summary.TotalUnavailableHours += dayUnavailable
+	}
+
+	summary.CompliantWeek = len(summary.Weeks) > 0
+	for i := range summary.Weeks {
+		w := &summary.Weeks[i]
+		w.Compliant = w.Reported && w.TotalHoursWithoutAltArrangement == 0
+		if !w.Compliant {
+			summary.CompliantWeek = false
+		}
+	}
+	summary.CompliantMonth = summary.CompliantWeek
+
+	if summary.TotalCoverageHours > 0 {
+		covered := summary.TotalCoverageHours - summary.TotalUnavailableHours
+		summary.CoveragePercentage = math.Round(covered/summary.TotalCoverageHours*10000) / 100
+	}
+
+	return summary, nil
+}
+
+// isoWeek identifies an ISO 8601 week
+type isoWeek struct {
+	year, week int
+}
+
+func weekOf(date time.Time) isoWeek {
+	year, week := date.ISOWeek()
+	return isoWeek{year, week}
+}
+
+// unavailableHours returns the length of a non-attendance period in hours.
+// An end time of 00:00:00 means the period ran to the end of the day.
+func unavailableHours(nat models.NonAttendanceTime) (float64, error) {
+	start, err := time.Parse("15:04:05", nat.UnavailableStartTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unavailableStartTime '%s'", nat.UnavailableStartTime)
+	}
+	end, err := time.Parse("15:04:05", nat.UnavailableEndTime)
+	if err != nil {
+		return 0, fmt.Errorf("invalid unavailableEndTime '%s'", nat.UnavailableEndTime)
+	}
+	if nat.UnavailableEndTime == "00:00:00" {
+		end = end.Add(24 * time.Hour)
+	}
+	if !end.After(start) {
+		return 0, fmt.Errorf("unavailableEndTime '%s' must be after unavailableStartTime '%s'", nat.UnavailableEndTime, nat.UnavailableStartTime)
+	}
+	return end.Sub(start).Hours(), nil
+}
+
+// hasAlternateArrangement reports whether care was covered while no RN was on site.
+// The deprecated alternateArrangement field is honoured for pre-July 2024 data.
+func hasAlternateArrangement(nat models.NonAttendanceTime) bool {
+	if nat.AlternateArrangement != "" {
+		return nat.AlternateArrangement != "No alternate care arrangements"
+	}
+	if nat.AccessToSupport == "" {
+		return false
+	}
+	return !strings.HasPrefix(nat.AccessToSupport, "9 -") && nat.AuthorityDelegatedTo != "No one"
+}
@@ -0,0 +1,88 @@
+package nurses
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+func TestSummarizeNotStartedMonth(t *testing.T) {
+	sub := newSubmission("Sub-240801-506", "SRV-00136", statusNotStarted, 2024, time.August, 1)
+
+	summary, err := summarizeSubmission(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalCoverageHours != 0 || summary.CoveragePercentage != 0 {
+		t.Errorf("unreported month has coverage %v hours (%v%%), want none", summary.TotalCoverageHours, summary.CoveragePercentage)
+	}
+	if summary.CompliantDay || summary.CompliantWeek || summary.CompliantMonth {
+		t.Errorf("unreported month is compliant: day %v, week %v, month %v", summary.CompliantDay, summary.CompliantWeek, summary.CompliantMonth)
+	}
+	// August 2024 starts on a Thursday: 1-4, 5-11, 12-18, 19-25 and 26-31
+	if len(summary.Weeks) != 5 {
+		t.Fatalf("got %d weeks, want 5", len(summary.Weeks))
+	}
+	for _, week := range summary.Weeks {
+		if week.Reported || week.Compliant {
+			t.Errorf("week %s to %s: reported %v, compliant %v, want neither", week.Start, week.End, week.Reported, week.Compliant)
+		}
+	}
+}
+
+func TestSummarizePartlyReportedMonth(t *testing.T) {
+	sub := newSubmission("Sub-240801-506", "SRV-00136", statusInProgress, 2024, time.August, 1)
+	for i := 0; i < 4; i++ {
+		sub.AttendanceDays[i].AttendanceDayStatus = dayNurseOnSite
+	}
+
+	summary, err := summarizeSubmission(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.TotalCoverageHours != 4*hoursPerDay || summary.CoveragePercentage != 100 {
+		t.Errorf("got %v hours at %v%%, want %v hours at 100%%", summary.TotalCoverageHours, summary.CoveragePercentage, 4*hoursPerDay)
+	}
+	if summary.CompliantDay || summary.CompliantWeek || summary.CompliantMonth {
+		t.Errorf("partly reported month is compliant: day %v, week %v, month %v", summary.CompliantDay, summary.CompliantWeek, summary.CompliantMonth)
+	}
+	if first := summary.Weeks[0]; first.Start != "2024-08-01" || first.End != "2024-08-04" || !first.Compliant {
+		t.Errorf("first week %+v, want 2024-08-01 to 2024-08-04 and compliant", first)
+	}
+	for _, week := range summary.Weeks[1:] {
+		if week.Compliant {
+			t.Errorf("unreported week %s to %s is compliant", week.Start, week.End)
+		}
+	}
+}
+
+func TestSummarizeWeekWithoutAlternateArrangement(t *testing.T) {
+	sub := newSubmission("Sub-240801-506", "SRV-00136", statusInProgress, 2024, time.August, 1)
+	for i := range sub.AttendanceDays {
+		sub.AttendanceDays[i].AttendanceDayStatus = dayNurseOnSite
+	}
+	// 14 August is in the third week
+	sub.AttendanceDays[13].AttendanceDayStatus = dayNurseNotOnSite
+	sub.AttendanceDays[13].NonAttendanceTime = []models.NonAttendanceTime{{
+		UnavailableStartTime: "09:00:00",
+		UnavailableEndTime:   "11:00:00",
+		AccessToSupport:      "9 - No access to support",
+	}}
+
+	summary, err := summarizeSubmission(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.CompliantDay || summary.CompliantWeek || summary.CompliantMonth {
+		t.Errorf("month with an uncovered gap is compliant: day %v, week %v, month %v", summary.CompliantDay, summary.CompliantWeek, summary.CompliantMonth)
+	}
+	for i, week := range summary.Weeks {
+		if want := i != 2; week.Compliant != want {
+			t.Errorf("week %s to %s: compliant %v, want %v", week.Start, week.End, week.Compliant, want)
+		}
+	}
+	if got := summary.Weeks[2].TotalHoursWithoutAltArrangement; got != 2 {
+		t.Errorf("third week has %v hours without an alternate arrangement, want 2", got)
+	}
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/search"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
+)
+
+// Register the references between providers and healthcare services for _include and _revinclude
+func init() {
+	// HealthcareService.providedBy -> Provider; "organization" is the FHIR search parameter name
+	search.RegisterInclude("HealthcareService", "HealthcareService:providedBy", includeProvidedBy)
+	search.RegisterInclude("HealthcareService", "HealthcareService:organization", includeProvidedBy)
+
+	// Provider <- HealthcareService.providedBy
+	search.RegisterRevInclude("Provider", "HealthcareService:providedBy", revIncludeServices)
+	search.RegisterRevInclude("Provider", "HealthcareService:organization", revIncludeServices)
+}
+
+// includeProvidedBy returns the provider referenced by a healthcare service
+func includeProvidedBy(r *http.Request, resource any) []search.Linked {
+	service, ok := resource.(models.HealthcareService)
+	if !ok {
+		return nil
+	}
+
+	organizationID := strings.TrimPrefix(service.ProvidedBy.Reference, "Organization/")
+	if !tokens.AllowsOrganization(r.Context(), organizationID) {
+		return nil
+	}
+	for _, provider := range mockProviders {
+		if provider.ID == organizationID {
+			return []search.Linked{{Endpoint: "Provider", ID: provider.ID, Resource: provider}}
+		}
+	}
+	return nil
+}
+
+// revIncludeServices returns the healthcare services provided by a provider
+func revIncludeServices(r *http.Request, resource any) []search.Linked {
+	provider, ok := resource.(models.Provider)
+	if !ok || !tokens.AllowsOrganization(r.Context(), provider.ID) {
+		return nil
+	}
+
+	var linked []search.Linked
+	for _, service := range mockHealthcareServices {
+		if service.ProvidedBy.Reference == "Organization/"+provider.ID {
+			linked = append(linked, search.Linked{Endpoint: "HealthcareService", ID: service.ID, Resource: service})
+		}
+	}
+	return linked
+}
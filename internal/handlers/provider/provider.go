@@ -2,17 +2,28 @@ package provider
 
 import (
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/search"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
 )
 
+// IntegrationIDSystem is the identifier system for GPMS service integration IDs (SRV-)
+const IntegrationIDSystem = "https://api.health.gov.au/integrationID"
+
+// ABNSystem is the identifier system for Australian Business Numbers
+const ABNSystem = "http://hl7.org.au/id/abn"
+
 // Mock data for providers
 var mockProviders = []models.Provider{
 	{
 		ID:           "PRV-12345",
 		ResourceType: "Organization",
+		Meta:         &models.Meta{LastUpdated: time.Date(2024, 3, 12, 9, 30, 0, 0, time.UTC)},
 		Identifier: []models.Identifier{
 			{
 				System: "http://ns.health.gov.au/id/hi/hpio",
@@ -22,6 +33,10 @@ var mockProviders = []models.Provider{
 				System: "http://ns.health.gov.au/id/provider/naps",
 				Value:  "PRV-12345",
 			},
+			{
+				System: ABNSystem,
+				Value:  "93605597126",
+			},
 		},
 		Active: true,
 		Type: []models.CodeableConcept{
@@ -66,6 +81,7 @@ var mockProviders = []models.Provider{
 	{
 		ID:           "PRV-67890",
 		ResourceType: "Organization",
+		Meta:         &models.Meta{LastUpdated: time.Date(2024, 6, 2, 14, 15, 0, 0, time.UTC)},
 		Identifier: []models.Identifier{
 			{
 				System: "http://ns.health.gov.au/id/hi/hpio",
@@ -75,6 +91,10 @@ var mockProviders = []models.Provider{
 				System: "http://ns.health.gov.au/id/provider/naps",
 				Value:  "PRV-67890",
 			},
+			{
+				System: ABNSystem,
+				Value:  "19100123456",
+			},
 		},
 		Active: true,
 		Type: []models.CodeableConcept{
@@ -123,11 +143,16 @@ var mockHealthcareServices = []models.HealthcareService{
 	{
 		ID:           "SVC-54321",
 		ResourceType: "HealthcareService",
+		Meta:         &models.Meta{LastUpdated: time.Date(2024, 5, 20, 11, 0, 0, 0, time.UTC)},
 		Identifier: []models.Identifier{
 			{
 				System: "http://ns.health.gov.au/id/service/aged-care",
 				Value:  "SVC-54321",
 			},
+			{
+				System: "https://api.health.gov.au/integrationID",
+				Value:  "SRV-00136",
+			},
 		},
 		Active: true,
 		ProvidedBy: models.Reference{
@@ -176,11 +201,16 @@ var mockHealthcareServices = []models.HealthcareService{
 	{
 		ID:           "SVC-98765",
 		ResourceType: "HealthcareService",
+		Meta:         &models.Meta{LastUpdated: time.Date(2023, 11, 8, 16, 45, 0, 0, time.UTC)},
 		Identifier: []models.Identifier{
 			{
 				System: "http://ns.health.gov.au/id/service/aged-care",
 				Value:  "SVC-98765",
 			},
+			{
+				System: "https://api.health.gov.au/integrationID",
+				Value:  "SRV-00138",
+			},
 		},
 		Active: true,
 		ProvidedBy: models.Reference{
@@ -229,11 +259,16 @@ var mockHealthcareServices = []models.HealthcareService{
 	{
 		ID:           "SVC-24680",
 		ResourceType: "HealthcareService",
+		Meta:         &models.Meta{LastUpdated: time.Date(2024, 7, 1, 8, 0, 0, 0, time.UTC)},
 		Identifier: []models.Identifier{
 			{
 				System: "http://ns.health.gov.au/id/service/aged-care",
 				Value:  "SVC-24680",
 			},
+			{
+				System: "https://api.health.gov.au/integrationID",
+				Value:  "SRV-00137",
+			},
 		},
 		Active: true,
 		ProvidedBy: models.Reference{
@@ -294,11 +329,53 @@ func RegisterHandlers(r chi.Router) {
 	})
 }
 
-// getProviders returns all providers
+// getProviders returns a searchset bundle of providers
 func getProviders(w http.ResponseWriter, r *http.Request) {
-	// In a real implementation, we would fetch providers from a database
-	// and filter by organization based on the JWT claims
-	render.JSON(w, r, mockProviders)
+	paging, err := search.ParsePaging(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	// Only return the providers the caller's token is bound to
+	allowed := []models.Provider{}
+	for _, provider := range mockProviders {
+		if tokens.AllowsOrganization(r.Context(), provider.ID) {
+			allowed = append(allowed, provider)
+		}
+	}
+
+	// Apply the FHIR search parameters and _sort
+	matches, err := providerSearch.Search(r.URL.Query(), allowed)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	bundle := search.NewBundle(r, "bundle-providers", len(matches), paging)
+	var page []any
+	for _, provider := range search.Paginate(matches, paging) {
+		resource, err := search.Elements(provider, r.URL.Query().Get("_elements"))
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "exception", err.Error()))
+			return
+		}
+		search.AddMatch(&bundle, search.FullURL(r, provider.ID), resource)
+		page = append(page, provider)
+	}
+
+	// Add the resources requested by _include and _revinclude
+	if err := search.AddIncludes(r, "Provider", &bundle, page); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-supported", err.Error()))
+		return
+	}
+
+	search.SetLinkHeader(w, bundle.Link)
+	render.JSON(w, r, bundle)
 }
 
 // getProviderByID returns a provider by ID
@@ -308,6 +385,11 @@ func getProviderByID(w http.ResponseWriter, r *http.Request) {
 	// Find provider by ID
 	for _, provider := range mockProviders {
 		if provider.ID == id {
+			if !tokens.AllowsOrganization(r.Context(), provider.ID) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+				return
+			}
 			render.JSON(w, r, provider)
 			return
 		}
@@ -318,27 +400,61 @@ func getProviderByID(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]string{"error": "Provider not found"})
 }
 
-// getHealthcareServices returns all healthcare services
+// getHealthcareServices returns a searchset bundle of healthcare services
 func getHealthcareServices(w http.ResponseWriter, r *http.Request) {
+	paging, err := search.ParsePaging(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
 	// Optional provider ID filter
 	providerID := r.URL.Query().Get("organization")
-
-	if providerID == "" {
-		// Return all services if no provider ID is specified
-		render.JSON(w, r, mockHealthcareServices)
+	if providerID != "" && !tokens.AllowsOrganization(r.Context(), providerID) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
 		return
 	}
 
-	// Filter services by provider ID
-	var filteredServices []models.HealthcareService
+	// Only consider services of organisations the caller's token is bound to
+	allowed := []models.HealthcareService{}
 	for _, service := range mockHealthcareServices {
-		// Check if the service's providedBy reference matches the provider ID
-		if service.ProvidedBy.Reference == "Organization/"+providerID {
-			filteredServices = append(filteredServices, service)
+		if tokens.AllowsOrganization(r.Context(), strings.TrimPrefix(service.ProvidedBy.Reference, "Organization/")) {
+			allowed = append(allowed, service)
 		}
 	}
 
-	render.JSON(w, r, filteredServices)
+	// Apply the FHIR search parameters (including organization) and _sort
+	filteredServices, err := healthcareServiceSearch.Search(r.URL.Query(), allowed)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	bundle := search.NewBundle(r, "bundle-healthcare-services", len(filteredServices), paging)
+	var page []any
+	for _, service := range search.Paginate(filteredServices, paging) {
+		resource, err := search.Elements(service, r.URL.Query().Get("_elements"))
+		if err != nil {
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "exception", err.Error()))
+			return
+		}
+		search.AddMatch(&bundle, search.FullURL(r, service.ID), resource)
+		page = append(page, service)
+	}
+
+	// Add the resources requested by _include and _revinclude
+	if err := search.AddIncludes(r, "HealthcareService", &bundle, page); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-supported", err.Error()))
+		return
+	}
+
+	search.SetLinkHeader(w, bundle.Link)
+	render.JSON(w, r, bundle)
 }
 
 // getHealthcareServiceByID returns a healthcare service by ID
@@ -348,6 +464,11 @@ func getHealthcareServiceByID(w http.ResponseWriter, r *http.Request) {
 	// Find service by ID
 	for _, service := range mockHealthcareServices {
 		if service.ID == id {
+			if !tokens.AllowsOrganization(r.Context(), strings.TrimPrefix(service.ProvidedBy.Reference, "Organization/")) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+				return
+			}
 			render.JSON(w, r, service)
 			return
 		}
@@ -357,3 +478,62 @@ func getHealthcareServiceByID(w http.ResponseWriter, r *http.Request) {
 	render.Status(r, http.StatusNotFound)
 	render.JSON(w, r, map[string]string{"error": "Healthcare Service not found"})
 }
+
+// ServiceIntegrationIDs returns the integration IDs (SRV-) of the healthcare
+// services provided by an organization (PRV-). Used by the RN attendance
+// search to resolve the organization filter.
+func ServiceIntegrationIDs(organizationID string) []string {
+	ids := []string{}
+	for _, service := range mockHealthcareServices {
+		if service.ProvidedBy.Reference != "Organization/"+organizationID {
+			continue
+		}
+		for _, identifier := range service.Identifier {
+			if identifier.System == IntegrationIDSystem {
+				ids = append(ids, identifier.Value)
+			}
+		}
+	}
+	return ids
+}
+
+// HealthcareServiceForIntegrationID returns the healthcare service with an integration ID (SRV-)
+func HealthcareServiceForIntegrationID(integrationID string) (models.HealthcareService, bool) {
+	for _, service := range mockHealthcareServices {
+		for _, identifier := range service.Identifier {
+			if identifier.System == IntegrationIDSystem && identifier.Value == integrationID {
+				return service, true
+			}
+		}
+	}
+	return models.HealthcareService{}, false
+}
+
+// OrganizationForABN returns the provider (PRV-) ID registered with an ABN
+func OrganizationForABN(abn string) (string, bool) {
+	for _, provider := range mockProviders {
+		for _, identifier := range provider.Identifier {
+			if identifier.System == ABNSystem && identifier.Value == abn {
+				return provider.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// OrganizationForService returns the provider (PRV-) ID of the organization
+// providing a healthcare service, looked up by service ID (SVC-) or integration ID (SRV-)
+func OrganizationForService(serviceID string) (string, bool) {
+	for _, service := range mockHealthcareServices {
+		matches := service.ID == serviceID
+		for _, identifier := range service.Identifier {
+			if identifier.Value == serviceID {
+				matches = true
+			}
+		}
+		if matches {
+			return strings.TrimPrefix(service.ProvidedBy.Reference, "Organization/"), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/search"
+)
+
+// providerSearch defines the search parameters supported on GET /Provider
+var providerSearch = search.Engine[models.Provider]{
+	Params: map[string]search.Param[models.Provider]{
+		"_id": {
+			Type:   search.TokenParam,
+			Tokens: func(p models.Provider) []search.Token { return []search.Token{{Code: p.ID}} },
+		},
+		"_lastUpdated": {
+			Type:  search.DateParam,
+			Dates: func(p models.Provider) []time.Time { return lastUpdated(p.Meta) },
+		},
+		"identifier": {
+			Type:   search.TokenParam,
+			Tokens: func(p models.Provider) []search.Token { return identifierTokens(p.Identifier) },
+		},
+		"name": {
+			Type:    search.StringParam,
+			Strings: func(p models.Provider) []string { return []string{p.Name} },
+		},
+		"active": {
+			Type:   search.TokenParam,
+			Tokens: func(p models.Provider) []search.Token { return []search.Token{{Code: strconv.FormatBool(p.Active)}} },
+		},
+		"type": {
+			Type:   search.TokenParam,
+			Tokens: func(p models.Provider) []search.Token { return conceptTokens(p.Type) },
+		},
+	},
+}
+
+// healthcareServiceSearch defines the search parameters supported on GET /HealthcareService
+var healthcareServiceSearch = search.Engine[models.HealthcareService]{
+	Params: map[string]search.Param[models.HealthcareService]{
+		"_id": {
+			Type:   search.TokenParam,
+			Tokens: func(s models.HealthcareService) []search.Token { return []search.Token{{Code: s.ID}} },
+		},
+		"_lastUpdated": {
+			Type:  search.DateParam,
+			Dates: func(s models.HealthcareService) []time.Time { return lastUpdated(s.Meta) },
+		},
+		"identifier": {
+			Type:   search.TokenParam,
+			Tokens: func(s models.HealthcareService) []search.Token { return identifierTokens(s.Identifier) },
+		},
+		"name": {
+			Type:    search.StringParam,
+			Strings: func(s models.HealthcareService) []string { return []string{s.Name} },
+		},
+		"active": {
+			Type: search.TokenParam,
+			Tokens: func(s models.HealthcareService) []search.Token {
+				return []search.Token{{Code: strconv.FormatBool(s.Active)}}
+			},
+		},
+		"type": {
+			Type:   search.TokenParam,
+			Tokens: func(s models.HealthcareService) []search.Token { return conceptTokens(s.Type) },
+		},
+		"organization": {
+			Type: search.TokenParam,
+			Tokens: func(s models.HealthcareService) []search.Token {
+				return []search.Token{{Code: strings.TrimPrefix(s.ProvidedBy.Reference, "Organization/")}}
+			},
+		},
+	},
+}
+
+func identifierTokens(identifiers []models.Identifier) []search.Token {
+	tokens := make([]search.Token, len(identifiers))
+	for i, identifier := range identifiers {
+		tokens[i] = search.Token{System: identifier.System, Code: identifier.Value}
+	}
+	return tokens
+}
+
+func conceptTokens(concepts []models.CodeableConcept) []search.Token {
+	var tokens []search.Token
+	for _, concept := range concepts {
+		for _, coding := range concept.Coding {
+			tokens = append(tokens, search.Token{System: coding.System, Code: coding.Code})
+		}
+	}
+	return tokens
+}
+
+func lastUpdated(meta *models.Meta) []time.Time {
+	if meta == nil {
+		return nil
+	}
+	return []time.Time{meta.LastUpdated}
+}
@@ -0,0 +1,256 @@
+package quality
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/webhooks"
+)
+
+// QuestionnaireResponse statuses
+const (
+	statusInProgress = "in-progress"
+	statusCompleted  = "completed"
+	statusAmended    = "amended"
+)
+
+// allowedTransitions lists the statuses a response may be moved to with PATCH
+var allowedTransitions = map[string][]string{
+	statusInProgress: {statusInProgress, statusCompleted},
+	statusCompleted:  {statusAmended},
+	statusAmended:    {statusAmended},
+}
+
+// patchQuestionnaireResponse merges the items in the request into a stored
+// response, moves it to the requested status and re-validates it
+func patchQuestionnaireResponse(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	patch, err := decodeResponsePatch(r.Body)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+	if patch.ID != "" && patch.ID != id {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Resource id '"+patch.ID+"' does not match '"+id+"'"))
+		return
+	}
+
+	responsesMu.Lock()
+	defer responsesMu.Unlock()
+
+	index := findResponse(id)
+	if index < 0 {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Questionnaire response not found"))
+		return
+	}
+	current := mockResponses[index]
+
+	if !subjectAllowed(r, current.Subject) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
+	}
+
+	// The questionnaire and subject identify the submission and cannot be changed
+	if patch.Questionnaire != "" && lastSegment(patch.Questionnaire) != lastSegment(current.Questionnaire) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "questionnaire cannot be changed"))
+		return
+	}
+	if patch.Subject.Reference != "" && lastSegment(patch.Subject.Reference) != lastSegment(current.Subject.Reference) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "subject cannot be changed"))
+		return
+	}
+
+	if locked, reason := isLocked(current); locked {
+		log.Printf("Rejected PATCH for locked QuestionnaireResponse %s: %s", id, reason)
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "lock-error", reason))
+		return
+	}
+
+	status, err := nextStatus(current.Status, patch.Status)
+	if err != nil {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "business-rule", err.Error()))
+		return
+	}
+
+	questionnaire, _ := findQuestionnaire(current.Questionnaire)
+	merged := current
+	merged.Item = orderItems(questionnaire.Item, mergeItems(current.Item, patch.Item))
+	merged.Extension = append([]models.Extension(nil), current.Extension...)
+	merged.Status = status
+	merged.AuthoredOn = clk.Now()
+
+	warnings, ok := checkResponse(w, r, merged)
+	if !ok {
+		return
+	}
+
+	// Record the first submitted date when a draft is completed
+	markSubmitted(&merged)
+	merged.Meta = resourceMeta(r)
+	if author, ok := federatedAuthor(r); ok {
+		merged.Author = author
+	}
+
+	mockResponses[index] = merged
+	webhooks.Emit(webhooks.QuestionnaireResponseAmended, merged)
+	renderResponse(w, r, http.StatusOK, merged, warnings, "updated")
+}
+
+// findResponse returns the index of the stored response with an ID, or -1.
+// Callers must hold responsesMu.
+func findResponse(id string) int {
+	for i, resp := range mockResponses {
+		if resp.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeResponsePatch decodes a PATCH body, which the specification sends as an
+// array holding a single QuestionnaireResponse; a bare object is also accepted
+func decodeResponsePatch(body io.Reader) (models.QuestionnaireResponse, error) {
+	var patch models.QuestionnaireResponse
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return patch, fmt.Errorf("invalid request body")
+	}
+	data = bytes.TrimSpace(data)
+
+	if len(data) > 0 && data[0] == '[' {
+		var patches []models.QuestionnaireResponse
+		if err := json.Unmarshal(data, &patches); err != nil {
+			return patch, fmt.Errorf("invalid request body")
+		}
+		if len(patches) != 1 {
+			return patch, fmt.Errorf("request body must contain exactly one QuestionnaireResponse")
+		}
+		return patches[0], nil
+	}
+
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return patch, fmt.Errorf("invalid request body")
+	}
+	return patch, nil
+}
+
+// nextStatus returns the status a response moves to. Without a requested
+// status, in-progress responses stay in progress and submitted ones become amended.
+func nextStatus(from, to string) (string, error) {
+	if to == "" {
+		if from == statusInProgress {
+			return statusInProgress, nil
+		}
+		to = statusAmended
+	}
+
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return to, nil
+		}
+	}
+	return "", fmt.Errorf("status cannot change from '%s' to '%s'", from, to)
+}
+
+// isLocked reports whether a response can no longer be amended, with the reason.
+// Submitted (completed or amended) responses lock once the due date of their
+// quarter has passed; in-progress ones can still be completed, late.
+func isLocked(resp models.QuestionnaireResponse) (bool, string) {
+	questionnaire, ok := findQuestionnaire(resp.Questionnaire)
+	if ok && questionnaire.Status == "retired" {
+		return true, "Questionnaire " + questionnaire.ID + " is retired and its submissions are locked"
+	}
+	if resp.Status == statusInProgress {
+		return false, ""
+	}
+	if q, ok := reportingQuarter(resp); ok && dateOf(clk.Now()).After(q.dueDate()) {
+		return true, fmt.Sprintf("QuestionnaireResponse %s was due on %s and can no longer be amended", resp.ID, q.dueDate().Format(dateLayout))
+	}
+	return false, ""
+}
+
+// mergeItems merges patch items into existing items by linkId. A patch item's
+// answers replace the existing answers when present ("answer": [] clears them),
+// and its nested items are merged recursively. New items are appended.
+// The existing slices are not modified.
+func mergeItems(existing, patch []models.QuestionnaireResponseItem) []models.QuestionnaireResponseItem {
+	merged := append([]models.QuestionnaireResponseItem(nil), existing...)
+
+	for _, p := range patch {
+		i := -1
+		for j, item := range merged {
+			if item.LinkID == p.LinkID {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			merged = append(merged, p)
+			continue
+		}
+
+		if p.Text != "" {
+			merged[i].Text = p.Text
+		}
+		if p.Answer != nil {
+			merged[i].Answer = p.Answer
+		}
+		if p.Item != nil {
+			merged[i].Item = mergeItems(merged[i].Item, p.Item)
+		}
+	}
+	return merged
+}
+
+// orderItems returns a copy of items sorted into questionnaire order, at every
+// level of nesting. Items not in the questionnaire are kept at the end.
+func orderItems(questions []models.QuestionnaireItem, items []models.QuestionnaireResponseItem) []models.QuestionnaireResponseItem {
+	position := map[string]int{}
+	children := map[string][]models.QuestionnaireItem{}
+	for i, question := range questions {
+		position[question.LinkID] = i
+		children[question.LinkID] = question.Item
+	}
+
+	ordered := append([]models.QuestionnaireResponseItem(nil), items...)
+	sort.SliceStable(ordered, func(a, b int) bool {
+		pa, okA := position[ordered[a].LinkID]
+		pb, okB := position[ordered[b].LinkID]
+		if okA && okB {
+			return pa < pb
+		}
+		return okA && !okB
+	})
+
+	for i := range ordered {
+		if len(ordered[i].Item) > 0 {
+			ordered[i].Item = orderItems(children[ordered[i].LinkID], ordered[i].Item)
+		}
+	}
+	return ordered
+}
+
+// lastSegment returns the last path segment of a reference, so that
+// "https://host/Questionnaire/QC-020", "Questionnaire/QC-020" and "QC-020" compare equal
+func lastSegment(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
@@ -0,0 +1,85 @@
+package quality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+func TestIsLockedAfterDueDate(t *testing.T) {
+	virtual := clock.NewVirtual()
+	clk = virtual
+	defer func() { clk = clock.System }()
+
+	// Q4 2024 is due on 21 January 2025
+	response := func(status string) models.QuestionnaireResponse {
+		return models.QuestionnaireResponse{
+			ID:            "QR-1",
+			Questionnaire: "QC-020",
+			Status:        status,
+			Extension: []models.Extension{
+				{URL: reportingPeriodURL, ValuePeriod: &models.DatePeriod{Start: "2024-10-01", End: "2024-12-31"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		now    string
+		status string
+		locked bool
+	}{
+		{"2025-01-21T23:00:00Z", statusCompleted, false},
+		{"2025-01-21T23:00:00Z", statusAmended, false},
+		{"2025-01-22T00:00:00Z", statusCompleted, true},
+		{"2025-01-22T00:00:00Z", statusAmended, true},
+		// In-progress responses can still be completed after the due date
+		{"2025-01-22T00:00:00Z", statusInProgress, false},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		virtual.Set(now, true)
+		if locked, reason := isLocked(response(tt.status)); locked != tt.locked {
+			t.Errorf("%s response at %s: locked %v (%q), want %v", tt.status, tt.now, locked, reason, tt.locked)
+		}
+	}
+}
+
+func TestIsLockedRetiredQuestionnaire(t *testing.T) {
+	resp := models.QuestionnaireResponse{ID: "QR-1", Questionnaire: "QC-20230630", Status: statusInProgress}
+	if locked, _ := isLocked(resp); !locked {
+		t.Error("response to a retired questionnaire is not locked")
+	}
+}
+
+func TestIsLockedFollowsClock(t *testing.T) {
+	// The day before Q4 2024 is due, set through the older QI_CURRENT_DATE
+	t.Setenv("MOCK_CLOCK", "")
+	t.Setenv("QI_CURRENT_DATE", "2025-01-20")
+	virtual := clock.FromEnv()
+	clk = virtual
+	defer func() { clk = clock.System }()
+
+	resp := models.QuestionnaireResponse{
+		ID:            "QR-1",
+		Questionnaire: "QC-020",
+		Status:        statusCompleted,
+		Extension: []models.Extension{
+			{URL: reportingPeriodURL, ValuePeriod: &models.DatePeriod{Start: "2024-10-01", End: "2024-12-31"}},
+		},
+	}
+	if locked, reason := isLocked(resp); locked {
+		t.Fatalf("locked before the due date: %s", reason)
+	}
+
+	virtual.Advance(48 * time.Hour)
+	if locked, _ := isLocked(resp); !locked {
+		t.Error("not locked after the clock moved past the due date")
+	}
+
+	virtual.Advance(-48 * time.Hour)
+	if locked, reason := isLocked(resp); locked {
+		t.Errorf("locked after the clock moved back: %s", reason)
+	}
+}
@@ -0,0 +1,123 @@
+package quality
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
+)
+
+// Query parameter patterns from the Quality Indicators OAS
+var searchParamPatterns = map[string]*regexp.Regexp{
+	"organization":    regexp.MustCompile(`^PRV-\d+$`),
+	"subject":         regexp.MustCompile(`^SRV-\d+$`),
+	"reporting-start": regexp.MustCompile(`^(19|20)\d{2}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01])$`),
+	"reporting-end":   regexp.MustCompile(`^(19|20)\d{2}-(0[1-9]|1[0-2])-(0[1-9]|[12]\d|3[01])$`),
+	// The X-Federated-Id of the end user who created or last amended a response
+	"author": regexp.MustCompile(`^\S+@\S+\.\S+$`),
+}
+
+// responseFilter holds the organization, subject and reporting period filters
+// of the QuestionnaireResponse endpoints
+type responseFilter struct {
+	organization string
+	subject      string
+	author       string
+	start, end   time.Time
+}
+
+// parseResponseFilter validates the QuestionnaireResponse filters against their
+// OAS patterns. The subject is required on these endpoints (required-subject).
+func parseResponseFilter(r *http.Request) (responseFilter, error) {
+	if err := validateSearchParams(r, "organization", "subject", "reporting-start", "reporting-end", "author"); err != nil {
+		return responseFilter{}, err
+	}
+
+	query := r.URL.Query()
+	f := responseFilter{
+		organization: query.Get("organization"),
+		subject:      query.Get("subject"),
+		author:       query.Get("author"),
+	}
+	if f.subject == "" {
+		return f, fmt.Errorf("subject is required")
+	}
+
+	// The patterns above guarantee the layout, but not that the day exists
+	var err error
+	if value := query.Get("reporting-start"); value != "" {
+		if f.start, err = time.Parse(dateLayout, value); err != nil {
+			return f, fmt.Errorf("invalid reporting-start '%s'", value)
+		}
+	}
+	if value := query.Get("reporting-end"); value != "" {
+		if f.end, err = time.Parse(dateLayout, value); err != nil {
+			return f, fmt.Errorf("invalid reporting-end '%s'", value)
+		}
+	}
+	if !f.start.IsZero() && !f.end.IsZero() && f.end.Before(f.start) {
+		return f, fmt.Errorf("reporting-end cannot be before reporting-start")
+	}
+	return f, nil
+}
+
+// matches reports whether a response is for the filtered subject and
+// organization, and whether its reporting quarter overlaps the filtered period
+func (f responseFilter) matches(resp models.QuestionnaireResponse) bool {
+	if serviceKey(resp.Subject) != serviceKey(models.Reference{Reference: f.subject}) {
+		return false
+	}
+	if f.organization != "" {
+		org, _ := provider.OrganizationForService(lastSegment(resp.Subject.Reference))
+		if org != f.organization {
+			return false
+		}
+	}
+	if f.author != "" && (resp.Author.Identifier == nil || !strings.EqualFold(resp.Author.Identifier.Value, f.author)) {
+		return false
+	}
+
+	if f.start.IsZero() && f.end.IsZero() {
+		return true
+	}
+	q, ok := reportingQuarter(resp)
+	if !ok {
+		return false
+	}
+	if !f.start.IsZero() && q.end().Before(f.start) {
+		return false
+	}
+	if !f.end.IsZero() && q.start.After(f.end) {
+		return false
+	}
+	return true
+}
+
+// validateSearchParams checks the named filters against their OAS patterns
+func validateSearchParams(r *http.Request, names ...string) error {
+	for _, name := range names {
+		value := r.URL.Query().Get(name)
+		if value != "" && !searchParamPatterns[name].MatchString(value) {
+			return fmt.Errorf("invalid %s '%s', must match %s", name, value, searchParamPatterns[name].String())
+		}
+	}
+	return nil
+}
+
+// scopeAllowed reports whether the caller's token grants access to the
+// organization and subject filters, where given
+func scopeAllowed(r *http.Request) bool {
+	if org := r.URL.Query().Get("organization"); org != "" && !tokens.AllowsOrganization(r.Context(), org) {
+		return false
+	}
+	if subject := r.URL.Query().Get("subject"); subject != "" {
+		org, _ := provider.OrganizationForService(subject)
+		return tokens.AllowsOrganization(r.Context(), org)
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package quality
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// QuestionnaireResponse extension URLs from the QI specification, plus
+// LateSubmission which the mock adds to responses first submitted after the due date
+const (
+	reportingPeriodURL    = "https://api.health.gov.au/fhir/structuredefinition/ReportingPeriod"
+	dueDateURL            = "https://api.health.gov.au/fhir/structuredefinition/DueDate"
+	firstSubmittedDateURL = "https://api.health.gov.au/fhir/structuredefinition/FirstSubmittedDate"
+	lateSubmissionURL     = "https://api.health.gov.au/fhir/structuredefinition/LateSubmission"
+)
+
+// dateLayout is the YYYY-MM-DD layout of reporting periods and due dates
+const dateLayout = "2006-01-02"
+
+// dueDay is the day of the month after the end of a quarter by which QI data must be submitted
+const dueDay = 21
+
+// quarter is a QI reporting quarter, identified by its first day
+type quarter struct {
+	start time.Time
+}
+
+// quarterOf returns the quarter containing a date
+func quarterOf(t time.Time) quarter {
+	month := time.Month((int(t.Month())-1)/3*3 + 1)
+	return quarter{start: time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// openQuarter returns the quarter providers are reporting on at a date: the
+// previous quarter until its due date has passed, then the current quarter
+func openQuarter(t time.Time) quarter {
+	current := quarterOf(t)
+	previous := quarter{start: current.start.AddDate(0, -3, 0)}
+	if !dateOf(t).After(previous.dueDate()) {
+		return previous
+	}
+	return current
+}
+
+// parseQuarter checks that a reporting period is exactly one quarter
+func parseQuarter(period models.DatePeriod) (quarter, error) {
+	start, err := time.Parse(dateLayout, period.Start)
+	if err != nil {
+		return quarter{}, fmt.Errorf("invalid reporting period start '%s', must be YYYY-MM-DD", period.Start)
+	}
+	q := quarterOf(start)
+	if !q.start.Equal(start) {
+		return quarter{}, fmt.Errorf("reporting period must start on the first day of a quarter, got '%s'", period.Start)
+	}
+	if period.End != "" && period.End != q.end().Format(dateLayout) {
+		return quarter{}, fmt.Errorf("reporting period starting %s must end on %s, got '%s'", period.Start, q.end().Format(dateLayout), period.End)
+	}
+	return q, nil
+}
+
+// end returns the last day of the quarter
+func (q quarter) end() time.Time {
+	return q.start.AddDate(0, 3, -1)
+}
+
+// dueDate returns the 21st day of the month after the end of the quarter
+func (q quarter) dueDate() time.Time {
+	return time.Date(q.start.Year(), q.start.Month()+3, dueDay, 0, 0, 0, 0, time.UTC)
+}
+
+func (q quarter) period() *models.DatePeriod {
+	return &models.DatePeriod{Start: q.start.Format(dateLayout), End: q.end().Format(dateLayout)}
+}
+
+// coveredBy reports whether a questionnaire's effective period includes the quarter
+func (q quarter) coveredBy(questionnaire models.Questionnaire) bool {
+	if questionnaire.EffectivePeriod == nil {
+		return true
+	}
+	if start, err := time.Parse(dateLayout, questionnaire.EffectivePeriod.Start); err == nil && q.start.Before(start) {
+		return false
+	}
+	if end, err := time.Parse(dateLayout, questionnaire.EffectivePeriod.End); err == nil && q.end().After(end) {
+		return false
+	}
+	return true
+}
+
+// dateOf truncates a time to its UTC date
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// assignQuarter ties a new response to its reporting quarter, taken from the
+// ReportingPeriod extension or defaulting to the open quarter, and sets the
+// reporting period and due date extensions
+func assignQuarter(resp *models.QuestionnaireResponse, questionnaire models.Questionnaire) error {
+	q := openQuarter(clk.Now())
+	if ext := findExtension(*resp, reportingPeriodURL); ext != nil && ext.ValuePeriod != nil {
+		var err error
+		if q, err = parseQuarter(*ext.ValuePeriod); err != nil {
+			return err
+		}
+	}
+
+	if !q.coveredBy(questionnaire) {
+		return fmt.Errorf("questionnaire %s does not apply to the reporting period %s to %s", questionnaire.ID, q.period().Start, q.period().End)
+	}
+
+	due := q.dueDate().Format(dateLayout)
+	setExtension(resp, models.Extension{URL: reportingPeriodURL, ValuePeriod: q.period()})
+	setExtension(resp, models.Extension{URL: dueDateURL, ValueDate: &due})
+	return nil
+}
+
+// reportingQuarter returns the quarter a stored response reports on
+func reportingQuarter(resp models.QuestionnaireResponse) (quarter, bool) {
+	ext := findExtension(resp, reportingPeriodURL)
+	if ext == nil || ext.ValuePeriod == nil {
+		return quarter{}, false
+	}
+	q, err := parseQuarter(*ext.ValuePeriod)
+	return q, err == nil
+}
+
+// markSubmitted records the first submitted date when a response is first
+// completed, and flags it as late when that is after the due date
+func markSubmitted(resp *models.QuestionnaireResponse) {
+	if resp.Status != statusCompleted {
+		return
+	}
+	if ext := findExtension(*resp, firstSubmittedDateURL); ext != nil && ext.ValueDate != nil {
+		return
+	}
+
+	today := dateOf(clk.Now())
+	submitted := today.Format(dateLayout)
+	setExtension(resp, models.Extension{URL: firstSubmittedDateURL, ValueDate: &submitted})
+
+	if q, ok := reportingQuarter(*resp); ok {
+		late := today.After(q.dueDate())
+		setExtension(resp, models.Extension{URL: lateSubmissionURL, ValueBoolean: &late})
+		if late {
+			log.Printf("QuestionnaireResponse %s for %s submitted late (due %s)", resp.ID, resp.Subject.Reference, q.dueDate().Format(dateLayout))
+		}
+	}
+}
+
+// findDuplicate returns the ID of a stored response for the same service and
+// quarter as resp. Callers must hold responsesMu.
+func findDuplicate(resp models.QuestionnaireResponse) (string, bool) {
+	q, ok := reportingQuarter(resp)
+	if !ok {
+		return "", false
+	}
+	for _, existing := range mockResponses {
+		if existing.ID == resp.ID || serviceKey(existing.Subject) != serviceKey(resp.Subject) {
+			continue
+		}
+		if eq, ok := reportingQuarter(existing); ok && eq.start.Equal(q.start) {
+			return existing.ID, true
+		}
+	}
+	return "", false
+}
+
+// serviceKey identifies the subject service, so that the service ID (SVC-) and
+// its integration ID (SRV-) are treated as the same service
+func serviceKey(subject models.Reference) string {
+	id := lastSegment(subject.Reference)
+	if strings.HasPrefix(id, "SRV-") {
+		if service, ok := provider.HealthcareServiceForIntegrationID(id); ok {
+			return service.ID
+		}
+	}
+	return id
+}
+
+func findExtension(resp models.QuestionnaireResponse, url string) *models.Extension {
+	for i := range resp.Extension {
+		if resp.Extension[i].URL == url {
+			return &resp.Extension[i]
+		}
+	}
+	return nil
+}
+
+// setExtension replaces the extension with the same URL, or appends it
+func setExtension(resp *models.QuestionnaireResponse, ext models.Extension) {
+	for i := range resp.Extension {
+		if resp.Extension[i].URL == ext.URL {
+			resp.Extension[i] = ext
+			return
+		}
+	}
+	resp.Extension = append(resp.Extension, ext)
+}
@@ -0,0 +1,1283 @@
+package quality
+
+import "github.com/jasonchiu/dohac-mock-apis/internal/models"
+
+// qiV2Questionnaire is the current quality indicators questionnaire (QI-V2)
+// from the 1.1.2 specification, covering the indicators reported through the
+// API. Enrolled nursing, lifestyle officer and allied health care minutes are
+// extracted from QFR reporting and are not part of the questionnaire.
+//
+// The breakdown items are conditional: they are only enabled, and required,
+// when the count they break down is above zero.
+var qiV2Questionnaire = models.Questionnaire{
+	ResourceType: "Questionnaire",
+	ID:           "QC-020",
+	URL:          "https://api.health.gov.au/quality-indicators/exp/v1/Questionnaire/QC-020",
+	Version:      "2",
+	Name:         "QI-V2",
+	Title:        "Quality Indicators Version 2",
+	Status:       "active",
+	Date:         "2024-10-01",
+	Publisher:    "Department of Health and Aged Care",
+	Description:  "Quality indicators questionnaire for the National Aged Care Mandatory Quality Indicator Program",
+	EffectivePeriod: &models.DatePeriod{
+		Start: "2024-10-01",
+	},
+	Item: []models.QuestionnaireItem{
+		{
+			LinkID:   "Pressure Injuries",
+			Text:     "Pressure Injuries",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "PIS-01",
+					Text:     "Pressure Injuries Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "PI-01",
+							Text:     "Number of care recipients assessed for pressure injuries",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-02",
+							Text:     "Number of care recipients excluded because they withheld consent to undergo an observation assessment for pressure injuries for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-03",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-04",
+							Text:     "Number of care recipients with one or more pressure injuries",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:     "PIS-02",
+					Text:       "Number of care recipients with one or more pressure injuries reported against each of the six pressure injury stages:",
+					Type:       "group",
+					Required:   true,
+					EnableWhen: whenPositive("PI-04"),
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "PI-05",
+							Text:     "Stage 1 pressure injury",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-06",
+							Text:     "Stage 2 pressure injury",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-07",
+							Text:     "Stage 3 pressure injury",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-08",
+							Text:     "Stage 4 pressure injury",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-09",
+							Text:     "Unstageable pressure injury",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PI-10",
+							Text:     "Suspected deep tissue injury",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "PIS-03",
+					Text:     "Number of care recipients with one or more pressure injuries acquired outside of the service during the quarter, reported against each of the six pressure injury stages:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "PI-11",
+							Text:     "Number of care recipients with one or more pressure injuries acquired outside of the service during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:     "PI-12",
+							Text:       "Stage 1 pressure injury",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("PI-11"),
+						},
+						{
+							LinkID:     "PI-13",
+							Text:       "Stage 2 pressure injury",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("PI-11"),
+						},
+						{
+							LinkID:     "PI-14",
+							Text:       "Stage 3 pressure injury",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("PI-11"),
+						},
+						{
+							LinkID:     "PI-15",
+							Text:       "Stage 4 pressure injury",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("PI-11"),
+						},
+						{
+							LinkID:     "PI-16",
+							Text:       "Unstageable pressure injury",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("PI-11"),
+						},
+						{
+							LinkID:     "PI-17",
+							Text:       "Suspected deep tissue injury",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("PI-11"),
+						},
+					},
+				},
+				{
+					LinkID:   "PIS-04",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "PI-18",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Physical Restraint",
+			Text:     "Physical Restraint",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "PRS-01",
+					Text:     "Physical Restraint Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "PR-01",
+							Text:     "The collection date for the quarter",
+							Type:     "date",
+							Required: true,
+						},
+						{
+							LinkID:   "PR-02",
+							Text:     "Number of care recipients whose records were assessed for Physical Restraint over the three-day assessment period.",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PR-03",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire three-day assessment period",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PR-04",
+							Text:     "Number of care recipients physically restrained (once or more and including through the use of secure areas) on any occasion during the three-day assessment period.",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "PR-05",
+							Text:     "Number of care recipients physically restrained during the three-day assessment period exclusively through the use of a secure area.",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "PRS-02",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "PR-06",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Unplanned Weight Loss",
+			Text:     "Unplanned Weight Loss",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "UPWLS-01",
+					Text:     "Significant unplanned weight loss",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "UPWL-01",
+							Text:     "Number of care recipients assessed for significant unplanned weight loss",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-02",
+							Text:     "Number of care recipients excluded because they withheld consent to be weighed on the finishing weight collection date",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-03",
+							Text:     "Number of care recipients excluded because they are receiving end-of-life care",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-04",
+							Text:     "Number of care recipients excluded because they did not have the required weights recorded (e.g. previous and/or finishing weights). Include comments as to why the weight recording/s are absent",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-05",
+							Text:     "Number of care recipients who experienced significant unplanned weight loss of 5% or more when comparing their finishing weight and previous weight",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-06",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+				{
+					LinkID:   "UPWLS-02",
+					Text:     "Consecutive unplanned weight loss",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "UPWL-08",
+							Text:     "Number of care recipients assessed for consecutive unplanned weight loss",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-09",
+							Text:     "Number of care recipients excluded because they withheld consent to be weighed on the starting, middle and/or finishing weight collection dates",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-10",
+							Text:     "Number of care recipients excluded because they are receiving end-of-life care",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-11",
+							Text:     "Number of care recipients excluded because they did not have the required weights recorded (e.g. previous, starting, middle and/or finishing weights). Include comments as to why the weight recording/s are absent",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-12",
+							Text:     "Number of care recipients who experienced consecutive unplanned weight loss of any amount when comparing their previous, starting, middle and finishing weights",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "UPWL-13",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Falls and Major Injury",
+			Text:     "Falls and Major Injury",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "FMIS-01",
+					Text:     "Falls and Major Injury Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "FMI-01",
+							Text:     "Number of care recipients assessed for falls and major injury",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "FMI-02",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "FMI-03",
+							Text:     "Number of care recipients who experienced one or more falls at the service during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "FMI-04",
+							Text:     "Number of care recipients who experienced one or more falls at the service resulting in major injury during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "FMI-05",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Medication Management",
+			Text:     "Medication Management",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "MMS-01",
+					Text:     "Polypharmacy",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "MM-01",
+							Text:     "The collection date for the quarter",
+							Type:     "date",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-02",
+							Text:     "Number of care recipients assessed for polypharmacy",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-03",
+							Text:     "Number of care recipients excluded because they were admitted to hospital on the collection date",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-04",
+							Text:     "Number of care recipients prescribed nine or more medications based on a review of their medication charts and/or administration records as they are on the collection date",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-05",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+				{
+					LinkID:   "MMS-02",
+					Text:     "Antipsychotics",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "MM-07",
+							Text:     "The collection date for the quarter",
+							Type:     "date",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-08",
+							Text:     "Number of care recipients assessed for antipsychotic medications",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-09",
+							Text:     "Number of care recipients excluded because they were admitted in hospital for the entire seven-day assessment period",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-10",
+							Text:     "Number of care recipients who received an antipsychotic medication",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-11",
+							Text:     "Number of care recipients who received an antipsychotic medication for a medically diagnosed condition of psychosis",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "MM-12",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Activities of Daily Living (ADLs)",
+			Text:     "Activities of Daily Living (ADLs)",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "ADLS-01",
+					Text:     "Activities of Daily Living Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "ADL-01",
+							Text:     "Number of care recipients assessed for ADL function (using the Barthel Index of Activities of Daily Living assessment tool)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "ADL-02",
+							Text:     "Number of care recipients excluded because they are receiving end-of-life care",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "ADL-03",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "ADL-04",
+							Text:     "Number of care recipients excluded because they did not have an ADL assessment total score recorded for the previous quarter. Include comments as to why the previous recording is absent",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "ADL-05",
+							Text:     "Number of care recipients with an ADL assessment total score of zero in the previous quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "ADL-06",
+							Text:     "Number of care recipients who experienced a decline in ADL assessment total score of one or more points",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "ADLS-02",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "ADL-07",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Incontinence Care",
+			Text:     "Incontinence Care",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "IADS-01",
+					Text:     "Incontinence Care Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "IAD-01",
+							Text:     "Number of care recipients assessed for incontinence care",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-02",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-03",
+							Text:     "Number of care recipients excluded from incontinence associated dermatitis (IAD) assessment because they did not have incontinence",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-04",
+							Text:     "Number of care recipients with incontinence",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-05",
+							Text:     "Number of care recipients with incontinence who experienced IAD",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:     "IADS-02",
+					Text:       "Number of care recipients with incontinence who experienced IAD, reported against each of the four IAD sub-categories",
+					Type:       "group",
+					Required:   true,
+					EnableWhen: whenPositive("IAD-05"),
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "IAD-06",
+							Text:     "1A: Persistent redness without clinical signs of infection",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-07",
+							Text:     "1B: Persistent redness with clinical signs of infection",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-08",
+							Text:     "2A: Skin loss without clinical signs of infection",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "IAD-09",
+							Text:     "2B: Skin loss with clinical signs of infection",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "IADS-03",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "IAD-10",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Hospitalisation",
+			Text:     "Hospitalisation",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "HPS-01",
+					Text:     "Hospitalisation Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "HP-01",
+							Text:     "Number of care recipients assessed for hospitalisation",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "HP-02",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "HP-03",
+							Text:     "Number of care recipients who had one or more emergency department presentations during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "HP-04",
+							Text:     "Number of care recipients who had one or more emergency department presentations or hospital admissions during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "HPS-02",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "HP-05",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Workforce",
+			Text:     "Workforce",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "WFS-01",
+					Text:     "Staff who worked any hours in previous quarter",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "WF-01",
+							Text:     "Number of staff who worked any hours as service managers in the previous quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-02",
+							Text:     "Number of staff who worked any hours as nurse practitioners or registered nurses in the previous quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-03",
+							Text:     "Number of staff who worked any hours as enrolled nurses in the previous quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-04",
+							Text:     "Number of staff who worked any hours as personal care staff or assistants in nursing in the previous quarter",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "WFS-02",
+					Text:     "Staff who were employed at the start of the quarter",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "WF-05",
+							Text:     "Number of staff employed as service managers at the start of the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-06",
+							Text:     "Number of staff employed as nurse practitioners or registered nurses at the start of the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-07",
+							Text:     "Number of staff employed as enrolled nurses at the start of the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-08",
+							Text:     "Number of staff employed as personal care staff or assistants in nursing at the start of the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "WFS-03",
+					Text:     "Staff employed who stopped work during the quarter",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "WF-09",
+							Text:     "Number of staff employed as service managers who stopped working during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-10",
+							Text:     "Number of staff employed as nurse practitioners or registered nurses who stopped working during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-11",
+							Text:     "Number of staff employed as enrolled nurses who stopped working during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "WF-12",
+							Text:     "Number of staff employed as personal care staff or assistants in nursing who stopped working during the quarter",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "WFS-04",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "WF-13",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Consumer Experience",
+			Text:     "Consumer Experience",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "CEIS-01",
+					Text:     "Consumer Experience Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "CEI-01",
+							Text:     "Number of care recipients offered a consumer experience assessment (QCE-ACC) through self-completion, interviewer facilitated completion or proxy completion",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-02",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-03",
+							Text:     "Number of care recipients excluded because they did not choose to complete the QCE-ACC for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "CEIS-02",
+					Text:     "Number of care recipients who reported consumer experience through self-completion of the QCE-ACC, scored against each of the five categories:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "CEI-04",
+							Text:     "‘Excellent’ (care recipients who score between 22–24)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-05",
+							Text:     "‘Good’ (care recipients who score between 19–21)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-06",
+							Text:     "‘Moderate’ (care recipients who score between 14–18)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-07",
+							Text:     "‘Poor’ (care recipients who score between 8–13)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-08",
+							Text:     "‘Very poor’ (care recipients who score between 0–7)",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "CEIS-03",
+					Text:     "Number of care recipients who reported consumer experience through interviewer facilitated completion of the QCE-ACC, scored against each of the five categories:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "CEI-09",
+							Text:     "‘Excellent’ (care recipients who score between 22–24)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-10",
+							Text:     "‘Good’ (care recipients who score between 19–21)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-11",
+							Text:     "‘Moderate’ (care recipients who score between 14–18)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-12",
+							Text:     "‘Poor’ (care recipients who score between 8–13)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-13",
+							Text:     "‘Very poor’ (care recipients who score between 0–7)",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "CEIS-04",
+					Text:     "Number of care recipients who reported consumer experience through proxy-completion of the QCE-ACC, scored against each of the five categories:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "CEI-14",
+							Text:     "‘Excellent’ (care recipients who score between 22–24)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-15",
+							Text:     "‘Good’ (care recipients who score between 19–21)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-16",
+							Text:     "‘Moderate’ (care recipients who score between 14–18)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-17",
+							Text:     "‘Poor’ (care recipients who score between 8–13)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "CEI-18",
+							Text:     "‘Very poor’ (care recipients who score between 0–7)",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "CEIS-05",
+					Text:     "Additional Details",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "CEI-19",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Quality of life",
+			Text:     "Quality of life",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "QOLS-01",
+					Text:     "Quality of Life Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "QOL-01",
+							Text:     "Number of care recipients offered a quality of life assessment (QOL-ACC) through self-completion, interviewer facilitated completion or proxy completion",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-02",
+							Text:     "Number of care recipients excluded because they were absent from the service for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-03",
+							Text:     "Number of care recipients excluded because they did not choose to complete the QOL-ACC for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "QOLS-02",
+					Text:     "Number of care recipients who reported quality of life through self-completion of the QOL-ACC, scored against each of the five categories:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "QOL-04",
+							Text:     "‘Excellent’ (care recipients who score between 22–24)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-05",
+							Text:     "‘Good’ (care recipients who score between 19–21)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-06",
+							Text:     "‘Moderate’ (care recipients who score between 14–18)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-07",
+							Text:     "‘Poor’ (care recipients who score between 8–13)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-08",
+							Text:     "‘Very poor’ (care recipients who score between 0–7)",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "QOLS-03",
+					Text:     "Number of care recipients who reported quality of life through interviewer facilitated completion of the QOL-ACC, scored against each of the five categories:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "QOL-09",
+							Text:     "‘Excellent’ (care recipients who score between 22–24)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-10",
+							Text:     "‘Good’ (care recipients who score between 19–21)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-11",
+							Text:     "‘Moderate’ (care recipients who score between 14–18)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-12",
+							Text:     "‘Poor’ (care recipients who score between 8–13)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-13",
+							Text:     "‘Very poor’ (care recipients who score between 0–7)",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "QOLS-04",
+					Text:     "Number of care recipients who reported quality of life through proxy-completion of the QOL-ACC, scored against each of the five categories:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "QOL-14",
+							Text:     "‘Excellent’ (care recipients who score between 22–24)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-15",
+							Text:     "‘Good’ (care recipients who score between 19–21)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-16",
+							Text:     "‘Moderate’ (care recipients who score between 14–18)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-17",
+							Text:     "‘Poor’ (care recipients who score between 8–13)",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "QOL-18",
+							Text:     "‘Very poor’ (care recipients who score between 0–7)",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "QOLS-05",
+					Text:     "Number of care recipients who reported QOL-ACC through proxy-completion",
+					Type:     "group",
+					Required: false,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "QOL-19",
+							Text:     "Comments",
+							Type:     "string",
+							Required: false,
+						},
+					},
+				},
+			},
+		},
+		{
+			LinkID:   "Allied Health",
+			Text:     "Allied Health",
+			Type:     "group",
+			Required: true,
+			Item: []models.QuestionnaireItem{
+				{
+					LinkID:   "AHS-01",
+					Text:     "Allied Health Details",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "AH-01",
+							Text:     "Number of care recipients assessed for allied health care",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-02",
+							Text:     "Number of care recipients excluded because they were absent for the entire quarter",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-03",
+							Text:     "Total Number of allied health services recommended in care plans",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:     "AHS-02",
+					Text:       "Number of allied health services recommended in care plans:",
+					Type:       "group",
+					Required:   true,
+					EnableWhen: whenPositive("AH-03"),
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "AH-04",
+							Text:     "Physiotherapy",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-05",
+							Text:     "Occupational therapy",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-06",
+							Text:     "Speech pathology",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-07",
+							Text:     "Podiatry",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-08",
+							Text:     "Dietetics",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-09",
+							Text:     "Other allied health",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:   "AH-10",
+							Text:     "Allied health assistant",
+							Type:     "integer",
+							Required: true,
+						},
+					},
+				},
+				{
+					LinkID:   "AHS-03",
+					Text:     "Number of recommended allied health services received:",
+					Type:     "group",
+					Required: true,
+					Item: []models.QuestionnaireItem{
+						{
+							LinkID:   "AH-11",
+							Text:     "Total Number of recommended allied health services received",
+							Type:     "integer",
+							Required: true,
+						},
+						{
+							LinkID:     "AH-12",
+							Text:       "Physiotherapy",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+						{
+							LinkID:     "AH-13",
+							Text:       "Occupational therapy",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+						{
+							LinkID:     "AH-14",
+							Text:       "Speech pathology",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+						{
+							LinkID:     "AH-15",
+							Text:       "Podiatry",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+						{
+							LinkID:     "AH-16",
+							Text:       "Dietetics",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+						{
+							LinkID:     "AH-17",
+							Text:       "Other allied health",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+						{
+							LinkID:     "AH-18",
+							Text:       "Allied health assistant",
+							Type:       "integer",
+							Required:   true,
+							EnableWhen: whenPositive("AH-11"),
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// whenPositive enables an item only when the integer answer to linkID is above zero
+func whenPositive(linkID string) []models.EnableWhen {
+	return []models.EnableWhen{{Question: linkID, Operator: ">", AnswerInteger: intPtr(0)}}
+}
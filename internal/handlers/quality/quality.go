@@ -2,25 +2,40 @@ package quality
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/handlers/provider"
+	"github.com/jasonchiu/dohac-mock-apis/internal/idgen"
 	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/search"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
+	"github.com/jasonchiu/dohac-mock-apis/internal/webhooks"
 )
 
-// Mock data for questionnaires
+// Mock data for questionnaires. QC-20230630 is kept for the responses that
+// were submitted against it; qiV2Questionnaire is the current question set.
 var mockQuestionnaires = []models.Questionnaire{
+	qiV2Questionnaire,
 	{
 		ResourceType: "Questionnaire",
 		ID:           "QC-20230630",
 		Name:         "quality-indicators-q4-2022-23",
 		Title:        "Quality Indicators Q4 2022-23",
-		Status:       "active",
+		Status:       "retired",
 		Date:         "2023-06-30",
 		Publisher:    "Department of Health and Aged Care",
 		Description:  "Quality indicators questionnaire for Q4 2022-23",
+		EffectivePeriod: &models.DatePeriod{
+			Start: "2023-04-01",
+			End:   "2023-06-30",
+		},
 		Item: []models.QuestionnaireItem{
 			{
 				LinkID:   "pressure-injuries",
@@ -162,6 +177,9 @@ var mockQuestionnaires = []models.Questionnaire{
 	},
 }
 
+// responsesMu guards mockResponses
+var responsesMu sync.Mutex
+
 // Mock data for questionnaire responses
 var mockResponses = []models.QuestionnaireResponse{
 	{
@@ -178,6 +196,12 @@ var mockResponses = []models.QuestionnaireResponse{
 			Reference: "Organization/PRV-12345",
 			Display:   "Sunset Aged Care",
 		},
+		Extension: []models.Extension{
+			{URL: reportingPeriodURL, ValuePeriod: &models.DatePeriod{Start: "2023-04-01", End: "2023-06-30"}},
+			{URL: dueDateURL, ValueDate: stringPtr("2023-07-21")},
+			{URL: firstSubmittedDateURL, ValueDate: stringPtr("2023-07-15")},
+			{URL: lateSubmissionURL, ValueBoolean: boolPtr(false)},
+		},
 		Item: []models.QuestionnaireResponseItem{
 			{
 				LinkID: "pressure-injuries",
@@ -188,7 +212,7 @@ var mockResponses = []models.QuestionnaireResponse{
 						Text:   "Number of residents who have developed a Stage 1 pressure injury during the quarter",
 						Answer: []models.QuestionnaireItemAnswer{
 							{
-								ValueInteger: 2,
+								ValueInteger: intPtr(2),
 							},
 						},
 					},
@@ -197,7 +221,7 @@ var mockResponses = []models.QuestionnaireResponse{
 						Text:   "Number of residents who have developed a Stage 2 pressure injury during the quarter",
 						Answer: []models.QuestionnaireItemAnswer{
 							{
-								ValueInteger: 1,
+								ValueInteger: intPtr(1),
 							},
 						},
 					},
@@ -206,7 +230,7 @@ var mockResponses = []models.QuestionnaireResponse{
 						Text:   "Number of residents who have developed a Stage 3 pressure injury during the quarter",
 						Answer: []models.QuestionnaireItemAnswer{
 							{
-								ValueInteger: 0,
+								ValueInteger: intPtr(0),
 							},
 						},
 					},
@@ -215,7 +239,7 @@ var mockResponses = []models.QuestionnaireResponse{
 						Text:   "Number of residents who have developed a Stage 4 pressure injury during the quarter",
 						Answer: []models.QuestionnaireItemAnswer{
 							{
-								ValueInteger: 0,
+								ValueInteger: intPtr(0),
 							},
 						},
 					},
@@ -224,7 +248,7 @@ var mockResponses = []models.QuestionnaireResponse{
 						Text:   "Any comments on pressure injuries data collection?",
 						Answer: []models.QuestionnaireItemAnswer{
 							{
-								ValueString: "Improved prevention measures implemented in this quarter.",
+								ValueString: stringPtr("Improved prevention measures implemented in this quarter."),
 							},
 						},
 					},
@@ -233,10 +257,72 @@ var mockResponses = []models.QuestionnaireResponse{
 			// More items for other categories...
 		},
 	},
+	{
+		ResourceType:  "QuestionnaireResponse",
+		ID:            "QR-20241022",
+		Questionnaire: "QC-020",
+		Status:        "in-progress",
+		Subject: models.Reference{
+			Reference: "HealthcareService/SVC-54321",
+			Display:   "Sunset Residential Care",
+		},
+		AuthoredOn: time.Date(2024, 10, 22, 9, 0, 0, 0, time.UTC),
+		Author: models.Reference{
+			Reference: "Organization/PRV-12345",
+			Display:   "Sunset Aged Care",
+		},
+		Extension: []models.Extension{
+			{URL: reportingPeriodURL, ValuePeriod: &models.DatePeriod{Start: "2024-10-01", End: "2024-12-31"}},
+			{URL: dueDateURL, ValueDate: stringPtr("2025-01-21")},
+		},
+		Item: []models.QuestionnaireResponseItem{
+			{
+				LinkID: "Pressure Injuries",
+				Text:   "Pressure Injuries",
+				Item: []models.QuestionnaireResponseItem{
+					{
+						LinkID: "PIS-01",
+						Text:   "Pressure Injuries Details",
+						Item: []models.QuestionnaireResponseItem{
+							{
+								LinkID: "PI-01",
+								Text:   "Number of care recipients assessed for pressure injuries",
+								Answer: []models.QuestionnaireItemAnswer{{ValueInteger: intPtr(129)}},
+							},
+							{
+								LinkID: "PI-02",
+								Text:   "Number of care recipients excluded because they withheld consent to undergo an observation assessment for pressure injuries for the entire quarter",
+								Answer: []models.QuestionnaireItemAnswer{{ValueInteger: intPtr(25)}},
+							},
+							{
+								LinkID: "PI-03",
+								Text:   "Number of care recipients excluded because they were absent from the service for the entire quarter",
+								Answer: []models.QuestionnaireItemAnswer{{ValueInteger: intPtr(20)}},
+							},
+							{
+								LinkID: "PI-04",
+								Text:   "Number of care recipients with one or more pressure injuries",
+								Answer: []models.QuestionnaireItemAnswer{{ValueInteger: intPtr(0)}},
+							},
+						},
+					},
+				},
+			},
+		},
+	},
 }
 
+// clk is the clock for authored dates and reporting deadlines, and ids
+// generates response IDs
+var (
+	clk = clock.System
+	ids = idgen.New()
+)
+
 // RegisterHandlers registers the quality indicators handlers
-func RegisterHandlers(r chi.Router) {
+func RegisterHandlers(r chi.Router, c clock.Clock, g *idgen.Generator) {
+	clk, ids = c, g
+
 	r.Route("/Questionnaire", func(r chi.Router) {
 		r.Get("/", getQuestionnaires)
 		r.Get("/{id}", getQuestionnaireByID)
@@ -246,24 +332,56 @@ func RegisterHandlers(r chi.Router) {
 		r.Get("/", getQuestionnaireResponses)
 		r.Post("/", createQuestionnaireResponse)
 		r.Get("/{id}", getQuestionnaireResponseByID)
+		r.Patch("/{id}", patchQuestionnaireResponse)
 	})
 }
 
-// getQuestionnaires returns all questionnaires
+// getQuestionnaires returns a searchset bundle of questionnaires
 func getQuestionnaires(w http.ResponseWriter, r *http.Request) {
-	// Optional organization filter
-	// org := r.URL.Query().Get("organization")
-	// subject := r.URL.Query().Get("subject")
+	// Questionnaires are shared by all services, so the optional organization
+	// and subject filters are only validated and checked against the token
+	if err := validateSearchParams(r, "organization", "subject"); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+	if !scopeAllowed(r) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
+	}
+
+	paging, err := search.ParsePaging(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	bundle := search.NewBundle(r, "bundle-questionnaires", len(mockQuestionnaires), paging)
+	for _, q := range search.Paginate(mockQuestionnaires, paging) {
+		search.AddMatch(&bundle, search.FullURL(r, q.ID), q)
+	}
 
-	// In a real implementation, we would filter by organization and subject
-	// For this mock, we'll just return all questionnaires
-	render.JSON(w, r, mockQuestionnaires)
+	search.SetLinkHeader(w, bundle.Link)
+	render.JSON(w, r, bundle)
 }
 
 // getQuestionnaireByID returns a questionnaire by ID
 func getQuestionnaireByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	if err := validateSearchParams(r, "organization", "subject"); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+	if !scopeAllowed(r) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
+	}
+
 	// Find questionnaire by ID
 	for _, q := range mockQuestionnaires {
 		if q.ID == id {
@@ -277,26 +395,68 @@ func getQuestionnaireByID(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, map[string]string{"error": "Questionnaire not found"})
 }
 
-// getQuestionnaireResponses returns all questionnaire responses
+// getQuestionnaireResponses returns a searchset bundle of questionnaire responses
 func getQuestionnaireResponses(w http.ResponseWriter, r *http.Request) {
-	// Optional filters
-	// org := r.URL.Query().Get("organization")
-	// subject := r.URL.Query().Get("subject")
-	// startDate := r.URL.Query().Get("reporting-start")
-	// endDate := r.URL.Query().Get("reporting-end")
-
-	// In a real implementation, we would filter by these parameters
-	// For this mock, we'll just return all responses
-	render.JSON(w, r, mockResponses)
+	filter, err := parseResponseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+	if !scopeAllowed(r) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
+	}
+
+	paging, err := search.ParsePaging(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	// Only return responses for services of the organisations the caller's token is bound to
+	responsesMu.Lock()
+	allowed := []models.QuestionnaireResponse{}
+	for _, resp := range mockResponses {
+		if subjectAllowed(r, resp.Subject) && filter.matches(resp) {
+			allowed = append(allowed, resp)
+		}
+	}
+	responsesMu.Unlock()
+
+	bundle := search.NewBundle(r, "bundle-questionnaire-responses", len(allowed), paging)
+	for _, resp := range search.Paginate(allowed, paging) {
+		search.AddMatch(&bundle, search.FullURL(r, resp.ID), resp)
+	}
+
+	search.SetLinkHeader(w, bundle.Link)
+	render.JSON(w, r, bundle)
 }
 
 // getQuestionnaireResponseByID returns a questionnaire response by ID
 func getQuestionnaireResponseByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	// Find response by ID
+	filter, err := parseResponseFilter(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", err.Error()))
+		return
+	}
+
+	responsesMu.Lock()
+	defer responsesMu.Unlock()
+
+	// Find response by ID; a response outside the filters is not found
 	for _, resp := range mockResponses {
-		if resp.ID == id {
+		if resp.ID == id && filter.matches(resp) {
+			if !subjectAllowed(r, resp.Subject) {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+				return
+			}
 			render.JSON(w, r, resp)
 			return
 		}
@@ -314,38 +474,157 @@ func createQuestionnaireResponse(w http.ResponseWriter, r *http.Request) {
 	// Decode JSON request
 	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "Invalid request body"})
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid request body"))
 		return
 	}
 
 	// Validate required fields
 	if resp.Questionnaire == "" || resp.Subject.Reference == "" {
 		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, map[string]string{"error": "questionnaire and subject are required"})
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "required", "questionnaire and subject are required"))
 		return
 	}
 
-	// In a real implementation, we would validate the response against the questionnaire
-	// and save it to a database
-
-	// Generate ID if not provided
-	if resp.ID == "" {
-		resp.ID = "QR-" + time.Now().Format("20060102150405")
+	// The subject must be a service of an organisation the caller's token is bound to
+	if !subjectAllowed(r, resp.Subject) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "forbidden", "User is forbidden to perform this action"))
+		return
 	}
 
 	// Set status to completed if not specified
 	if resp.Status == "" {
-		resp.Status = "completed"
+		resp.Status = statusCompleted
+	}
+
+	// New responses are either a draft or a completed submission; amendments use PATCH
+	if resp.Status != statusInProgress && resp.Status != statusCompleted {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "business-rule", "status must be '"+statusInProgress+"' or '"+statusCompleted+"' when creating a QuestionnaireResponse"))
+		return
+	}
+
+	// Tie the response to its reporting quarter
+	if questionnaire, ok := findQuestionnaire(resp.Questionnaire); ok {
+		if err := assignQuarter(&resp, questionnaire); err != nil {
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "business-rule", err.Error()))
+			return
+		}
+	}
+
+	// Validate the answers against the questionnaire and the business rules
+	warnings, ok := checkResponse(w, r, resp)
+	if !ok {
+		return
 	}
 
 	// Set authored date if not specified
 	if resp.AuthoredOn.IsZero() {
-		resp.AuthoredOn = time.Now()
+		resp.AuthoredOn = clk.Now()
+	}
+
+	responsesMu.Lock()
+	defer responsesMu.Unlock()
+
+	// A provided ID must not be in use; generated IDs skip any that clients have taken
+	if resp.ID != "" && findResponse(resp.ID) >= 0 {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "duplicate", "QuestionnaireResponse "+resp.ID+" already exists"))
+		return
+	}
+	for resp.ID == "" || findResponse(resp.ID) >= 0 {
+		resp.ID = ids.QuestionnaireResponse()
+	}
+
+	// Only one response is accepted per service and quarter; later changes use PATCH
+	if existing, ok := findDuplicate(resp); ok {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "duplicate", "QuestionnaireResponse "+existing+" already exists for this service and reporting period"))
+		return
+	}
+
+	// Record the first submitted date and whether it was late
+	markSubmitted(&resp)
+	resp.Meta = resourceMeta(r)
+	if author, ok := federatedAuthor(r); ok {
+		resp.Author = author
 	}
 
 	// Add to mock responses
 	mockResponses = append(mockResponses, resp)
+	webhooks.Emit(webhooks.QuestionnaireResponseCreated, resp)
+
+	renderResponse(w, r, http.StatusCreated, resp, warnings, "created")
+}
+
+// checkResponse validates a response against its questionnaire and the QI
+// business rules, writing a 422 OperationOutcome when it is rejected. Business
+// rule warnings are returned for the caller to report.
+func checkResponse(w http.ResponseWriter, r *http.Request, resp models.QuestionnaireResponse) ([]models.Issue, bool) {
+	questionnaire, ok := findQuestionnaire(resp.Questionnaire)
+	if !ok {
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", "Questionnaire '"+resp.Questionnaire+"' not found"))
+		return nil, false
+	}
+	if issues := validateResponse(questionnaire, resp); len(issues) > 0 {
+		log.Printf("Rejected QuestionnaireResponse for %s with %d issue(s)", resp.Subject.Reference, len(issues))
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.OperationOutcome{ResourceType: "OperationOutcome", Issue: issues})
+		return nil, false
+	}
+
+	// Warnings do not block the submission
+	ruleErrors, warnings := evaluateRules(questionnaire, resp)
+	if len(ruleErrors) > 0 {
+		log.Printf("Rejected QuestionnaireResponse for %s with %d business rule error(s)", resp.Subject.Reference, len(ruleErrors))
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, models.OperationOutcome{ResourceType: "OperationOutcome", Issue: append(ruleErrors, warnings...)})
+		return nil, false
+	}
+	return warnings, true
+}
 
-	render.Status(r, http.StatusCreated)
+// renderResponse writes a stored response, or with Prefer: return=OperationOutcome
+// an OperationOutcome reporting the action and any business rule warnings
+func renderResponse(w http.ResponseWriter, r *http.Request, status int, resp models.QuestionnaireResponse, warnings []models.Issue, action string) {
+	render.Status(r, status)
+	if strings.Contains(r.Header.Get("Prefer"), "return=OperationOutcome") {
+		outcome := models.NewOperationOutcome("INFORMATION", "informational", "QuestionnaireResponse "+resp.ID+" "+action)
+		outcome.Issue = append(outcome.Issue, warnings...)
+		render.JSON(w, r, outcome)
+		return
+	}
 	render.JSON(w, r, resp)
 }
+
+// resourceMeta returns the metadata of a response stored by the current call,
+// with its transaction_id as the source
+func resourceMeta(r *http.Request) *models.Meta {
+	return &models.Meta{LastUpdated: clk.Now(), Source: "#" + r.Header.Get("transaction_id")}
+}
+
+// federatedIDSystem identifies author references by email address (RFC 5322)
+const federatedIDSystem = "urn:ietf:rfc:5322"
+
+// federatedAuthor returns the end user named by the X-Federated-Id header as
+// an author reference, identified by their email address
+func federatedAuthor(r *http.Request) (models.Reference, bool) {
+	email := r.Header.Get("X-Federated-Id")
+	if email == "" {
+		return models.Reference{}, false
+	}
+	return models.Reference{
+		Identifier: &models.Identifier{System: federatedIDSystem, Value: email},
+		Display:    email,
+	}, true
+}
+
+// subjectAllowed reports whether the caller's token grants access to the
+// organisation providing the subject service (e.g. "HealthcareService/SVC-54321")
+func subjectAllowed(r *http.Request, subject models.Reference) bool {
+	serviceID := subject.Reference[strings.LastIndex(subject.Reference, "/")+1:]
+	org, _ := provider.OrganizationForService(serviceID)
+	return tokens.AllowsOrganization(r.Context(), org)
+}
@@ -0,0 +1,291 @@
+package quality
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// upwlWarningBug reproduces the known issue in the real QI API, where the
+// UPWL-04 and UPWL-11 warnings asking for a comment are returned even when the
+// comment (UPWL-06 or UPWL-13) has been supplied. It is on by default; set
+// QI_UPWL_WARNING_BUG=false to get the corrected behaviour. The variable is
+// only read at startup, so changing it needs a restart.
+var upwlWarningBug = os.Getenv("QI_UPWL_WARNING_BUG") != "false"
+
+// rule is a cross-field business rule for one quality indicator. Rules are
+// skipped when an item they depend on has not been answered.
+type rule struct {
+	indicator string
+	severity  string
+	// linkID is the item the issue is reported against
+	linkID string
+	// check returns a message when the rule is broken, or "" when it holds
+	check func(a ruleAnswers) string
+}
+
+// questionnaireRules are the business rules for each questionnaire ID. The
+// linkIds differ between questionnaire versions, so each version has its own
+// rules; responses to questionnaires without rules only get the structural
+// validation.
+var questionnaireRules = map[string][]rule{
+	"QC-20230630": q4202223Rules,
+	"QC-020":      qiV2Rules,
+}
+
+// q4202223Rules are the business rules for the Q4 2022-23 questionnaire
+var q4202223Rules = []rule{
+	atMost("Unplanned Weight Loss", "UPWL-02", "UPWL-01"),
+	atMost("Falls and Major Injury", "FMI-02", "FMI-01"),
+}
+
+// qiV2Rules are the business rules for the 1.1.2 (QI-V2) questionnaire
+var qiV2Rules = concatRules(
+	// Pressure injuries
+	[]rule{atMost("Pressure Injuries", "PI-04", "PI-01")},
+	eachAtMost("Pressure Injuries", "PI-04", "PI-05", "PI-06", "PI-07", "PI-08", "PI-09", "PI-10", "PI-11"),
+	eachAtMost("Pressure Injuries", "PI-11", "PI-12", "PI-13", "PI-14", "PI-15", "PI-16", "PI-17"),
+
+	// Physical restraint
+	[]rule{
+		atMost("Physical Restraint", "PR-04", "PR-02"),
+		atMost("Physical Restraint", "PR-05", "PR-04"),
+	},
+
+	// Unplanned weight loss
+	[]rule{
+		atMost("Unplanned Weight Loss", "UPWL-05", "UPWL-01"),
+		atMost("Unplanned Weight Loss", "UPWL-12", "UPWL-08"),
+		commentWarning("Unplanned Weight Loss", "UPWL-04", "UPWL-06", &upwlWarningBug),
+		commentWarning("Unplanned Weight Loss", "UPWL-11", "UPWL-13", &upwlWarningBug),
+	},
+
+	// Falls and major injury
+	[]rule{
+		atMost("Falls and Major Injury", "FMI-03", "FMI-01"),
+		atMost("Falls and Major Injury", "FMI-04", "FMI-03"),
+	},
+
+	// Medication management
+	[]rule{
+		atMost("Medication Management", "MM-04", "MM-02"),
+		atMost("Medication Management", "MM-10", "MM-08"),
+		atMost("Medication Management", "MM-11", "MM-10"),
+	},
+
+	// Activities of daily living
+	[]rule{
+		atMost("Activities of Daily Living (ADLs)", "ADL-05", "ADL-01"),
+		atMost("Activities of Daily Living (ADLs)", "ADL-06", "ADL-01"),
+	},
+
+	// Incontinence care
+	[]rule{
+		atMost("Incontinence Care", "IAD-04", "IAD-01"),
+		atMost("Incontinence Care", "IAD-05", "IAD-04"),
+	},
+	eachAtMost("Incontinence Care", "IAD-05", "IAD-06", "IAD-07", "IAD-08", "IAD-09"),
+
+	// Hospitalisation. HP-03 is not checked against HP-04: the specification's
+	// example submission reports more ED presentations than the combined count.
+	[]rule{
+		atMost("Hospitalisation", "HP-03", "HP-01"),
+		atMost("Hospitalisation", "HP-04", "HP-01"),
+	},
+
+	// Consumer experience and quality of life
+	[]rule{
+		sumAtMost("Consumer Experience", "CEI-01",
+			"CEI-04", "CEI-05", "CEI-06", "CEI-07", "CEI-08",
+			"CEI-09", "CEI-10", "CEI-11", "CEI-12", "CEI-13",
+			"CEI-14", "CEI-15", "CEI-16", "CEI-17", "CEI-18"),
+		sumAtMost("Quality of life", "QOL-01",
+			"QOL-04", "QOL-05", "QOL-06", "QOL-07", "QOL-08",
+			"QOL-09", "QOL-10", "QOL-11", "QOL-12", "QOL-13",
+			"QOL-14", "QOL-15", "QOL-16", "QOL-17", "QOL-18"),
+	},
+
+	// Allied health
+	[]rule{
+		sumEquals("Allied Health", "AH-03", "AH-04", "AH-05", "AH-06", "AH-07", "AH-08", "AH-09", "AH-10"),
+		sumEquals("Allied Health", "AH-11", "AH-12", "AH-13", "AH-14", "AH-15", "AH-16", "AH-17", "AH-18"),
+		atMost("Allied Health", "AH-11", "AH-03"),
+		atMost("Allied Health", "AH-12", "AH-04"),
+		atMost("Allied Health", "AH-13", "AH-05"),
+		atMost("Allied Health", "AH-14", "AH-06"),
+		atMost("Allied Health", "AH-15", "AH-07"),
+		atMost("Allied Health", "AH-16", "AH-08"),
+		atMost("Allied Health", "AH-17", "AH-09"),
+		atMost("Allied Health", "AH-18", "AH-10"),
+	},
+)
+
+// evaluateRules runs the business rules against a response and returns the
+// broken rules split into errors, which reject the submission, and warnings
+func evaluateRules(q models.Questionnaire, resp models.QuestionnaireResponse) (errors, warnings []models.Issue) {
+	answers := ruleAnswers{}
+	indexAnswers(resp.Item, answers)
+
+	paths := map[string]string{}
+	indexPaths(q.Item, "", paths)
+
+	for _, rule := range questionnaireRules[q.ID] {
+		message := rule.check(answers)
+		if message == "" {
+			continue
+		}
+
+		path := paths[rule.linkID]
+		if path == "" {
+			path = rule.linkID
+		}
+		issue := models.Issue{
+			Severity:   rule.severity,
+			Code:       "business-rule",
+			Details:    &models.IssueDetails{Text: fmt.Sprintf("%s: %s", rule.indicator, message)},
+			Expression: []string{path},
+		}
+		if rule.severity == "WARNING" {
+			warnings = append(warnings, issue)
+		} else {
+			errors = append(errors, issue)
+		}
+	}
+	return errors, warnings
+}
+
+// indexPaths maps every linkId in a questionnaire to its linkId path
+func indexPaths(items []models.QuestionnaireItem, parent string, paths map[string]string) {
+	for _, item := range items {
+		path := joinPath(parent, item.LinkID)
+		paths[item.LinkID] = path
+		indexPaths(item.Item, path, paths)
+	}
+}
+
+// ruleAnswers indexes the answers of a response by linkId
+type ruleAnswers map[string][]models.QuestionnaireItemAnswer
+
+// integer returns the integer answer to an item
+func (a ruleAnswers) integer(linkID string) (int, bool) {
+	for _, answer := range a[linkID] {
+		if answer.ValueInteger != nil {
+			return *answer.ValueInteger, true
+		}
+	}
+	return 0, false
+}
+
+// text returns the trimmed string answer to an item
+func (a ruleAnswers) text(linkID string) string {
+	for _, answer := range a[linkID] {
+		if answer.ValueString != nil {
+			return strings.TrimSpace(*answer.ValueString)
+		}
+	}
+	return ""
+}
+
+// atMost requires the count in linkID to be no greater than the count in limit
+func atMost(indicator, linkID, limit string) rule {
+	return rule{
+		indicator: indicator,
+		severity:  "ERROR",
+		linkID:    linkID,
+		check: func(a ruleAnswers) string {
+			value, ok := a.integer(linkID)
+			max, maxOK := a.integer(limit)
+			if !ok || !maxOK || value <= max {
+				return ""
+			}
+			return fmt.Sprintf("%s (%d) cannot be greater than %s (%d)", linkID, value, limit, max)
+		},
+	}
+}
+
+// eachAtMost applies atMost to every item in linkIDs
+func eachAtMost(indicator, limit string, linkIDs ...string) []rule {
+	rules := make([]rule, len(linkIDs))
+	for i, linkID := range linkIDs {
+		rules[i] = atMost(indicator, linkID, limit)
+	}
+	return rules
+}
+
+// sumAtMost requires the total of the parts to be no greater than the count in limit
+func sumAtMost(indicator, limit string, parts ...string) rule {
+	return rule{
+		indicator: indicator,
+		severity:  "ERROR",
+		linkID:    limit,
+		check: func(a ruleAnswers) string {
+			total, ok := sum(a, parts)
+			max, maxOK := a.integer(limit)
+			if !ok || !maxOK || total <= max {
+				return ""
+			}
+			return fmt.Sprintf("the total of %s to %s (%d) cannot be greater than %s (%d)", parts[0], parts[len(parts)-1], total, limit, max)
+		},
+	}
+}
+
+// sumEquals requires the parts to add up to the count in total
+func sumEquals(indicator, total string, parts ...string) rule {
+	return rule{
+		indicator: indicator,
+		severity:  "ERROR",
+		linkID:    total,
+		check: func(a ruleAnswers) string {
+			got, ok := sum(a, parts)
+			want, wantOK := a.integer(total)
+			if !ok || !wantOK || got == want {
+				return ""
+			}
+			return fmt.Sprintf("%s (%d) must equal the total of %s to %s (%d)", total, want, parts[0], parts[len(parts)-1], got)
+		},
+	}
+}
+
+// commentWarning warns when the count in linkID is above zero and no comment
+// explaining it has been given. When *ignoreComment is set the warning is
+// returned whether or not the comment was given.
+func commentWarning(indicator, linkID, comment string, ignoreComment *bool) rule {
+	return rule{
+		indicator: indicator,
+		severity:  "WARNING",
+		linkID:    linkID,
+		check: func(a ruleAnswers) string {
+			value, ok := a.integer(linkID)
+			if !ok || value == 0 {
+				return ""
+			}
+			if a.text(comment) != "" && !*ignoreComment {
+				return ""
+			}
+			return fmt.Sprintf("%s is %d, please provide a comment in %s", linkID, value, comment)
+		},
+	}
+}
+
+// sum adds up the integer answers to all parts; every part must be answered
+func sum(a ruleAnswers, parts []string) (int, bool) {
+	total := 0
+	for _, part := range parts {
+		value, ok := a.integer(part)
+		if !ok {
+			return 0, false
+		}
+		total += value
+	}
+	return total, true
+}
+
+func concatRules(groups ...[]rule) []rule {
+	var rules []rule
+	for _, group := range groups {
+		rules = append(rules, group...)
+	}
+	return rules
+}
@@ -0,0 +1,92 @@
+package quality
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// integerItems builds response items with integer answers, e.g. integerItems("PI-01", 3)
+func integerItems(pairs ...interface{}) []models.QuestionnaireResponseItem {
+	var items []models.QuestionnaireResponseItem
+	for i := 0; i < len(pairs); i += 2 {
+		items = append(items, answered(pairs[i].(string), models.QuestionnaireItemAnswer{ValueInteger: intPtr(pairs[i+1].(int))}))
+	}
+	return items
+}
+
+// sequence returns linkIds prefix-from to prefix-to, e.g. CEI-04 to CEI-18
+func sequence(prefix string, from, to int) []string {
+	var linkIDs []string
+	for i := from; i <= to; i++ {
+		linkIDs = append(linkIDs, fmt.Sprintf("%s-%02d", prefix, i))
+	}
+	return linkIDs
+}
+
+func TestQIV2Rules(t *testing.T) {
+	q, ok := findQuestionnaire("QC-020")
+	if !ok {
+		t.Fatal("QC-020 questionnaire not found")
+	}
+
+	// Fifteen CEI-04 to CEI-18 answers of 1 total 15
+	cei := []interface{}{}
+	for _, linkID := range sequence("CEI", 4, 18) {
+		cei = append(cei, linkID, 1)
+	}
+
+	tests := []struct {
+		name  string
+		items []models.QuestionnaireResponseItem
+		// broken is the linkId the error is reported against, or "" for none
+		broken string
+	}{
+		{"atMost holds", integerItems("PI-01", 3, "PI-04", 3), ""},
+		{"atMost broken", integerItems("PI-01", 3, "PI-04", 4), "PI-04"},
+		{"atMost unanswered limit", integerItems("PI-04", 4), ""},
+		{"sumAtMost holds", integerItems(append(cei, "CEI-01", 15)...), ""},
+		{"sumAtMost broken", integerItems(append(cei, "CEI-01", 14)...), "CEI-01"},
+		{"sumAtMost missing part", integerItems(append(cei[2:], "CEI-01", 1)...), ""},
+		{"sumEquals broken", integerItems("AH-03", 5, "AH-04", 1, "AH-05", 1, "AH-06", 1, "AH-07", 1, "AH-08", 0, "AH-09", 0, "AH-10", 0), "AH-03"},
+	}
+	for _, tt := range tests {
+		errors, _ := evaluateRules(q, models.QuestionnaireResponse{Item: tt.items})
+		switch {
+		case tt.broken == "" && len(errors) > 0:
+			t.Errorf("%s: got %v, want no errors", tt.name, errors)
+		case tt.broken != "" && len(errors) != 1:
+			t.Errorf("%s: got %v, want one error against %s", tt.name, errors, tt.broken)
+		case tt.broken != "":
+			if issue := errors[0]; issue.Severity != "ERROR" || issue.Code != "business-rule" || !strings.HasSuffix(issue.Expression[0], tt.broken) {
+				t.Errorf("%s: got %+v, want a business-rule error against %s", tt.name, issue, tt.broken)
+			}
+		}
+	}
+}
+
+func TestUPWLWarningBug(t *testing.T) {
+	q, _ := findQuestionnaire("QC-020")
+	defer func(bug bool) { upwlWarningBug = bug }(upwlWarningBug)
+
+	comment := answered("UPWL-06", models.QuestionnaireItemAnswer{ValueString: stringPtr("Reviewed by dietitian")})
+	tests := []struct {
+		bug      bool
+		items    []models.QuestionnaireResponseItem
+		warnings int
+	}{
+		{false, integerItems("UPWL-04", 2), 1},
+		{false, append(integerItems("UPWL-04", 2), comment), 0},
+		{false, integerItems("UPWL-04", 0), 0},
+		{true, append(integerItems("UPWL-04", 2), comment), 1},
+	}
+	for _, tt := range tests {
+		upwlWarningBug = tt.bug
+		_, warnings := evaluateRules(q, models.QuestionnaireResponse{Item: tt.items})
+		if len(warnings) != tt.warnings {
+			t.Errorf("bug %v with %d items: got warnings %v, want %d", tt.bug, len(tt.items), warnings, tt.warnings)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package quality
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// datePattern is the FHIR date format used by valueDate answers
+var datePattern = regexp.MustCompile(`^\d{4}(-(0[1-9]|1[0-2])(-(0[1-9]|[12]\d|3[01]))?)?$`)
+
+func intPtr(v int) *int          { return &v }
+func stringPtr(v string) *string { return &v }
+func boolPtr(v bool) *bool       { return &v }
+
+// findQuestionnaire returns the questionnaire a response refers to, given as
+// an ID, a relative reference ("Questionnaire/QC-20230630") or a canonical URL
+func findQuestionnaire(ref string) (models.Questionnaire, bool) {
+	for _, q := range mockQuestionnaires {
+		if q.ID == lastSegment(ref) {
+			return q, true
+		}
+	}
+	return models.Questionnaire{}, false
+}
+
+// responseValidator checks a QuestionnaireResponse against its Questionnaire
+type responseValidator struct {
+	// answers indexes the answers with a value in the response by linkId, for enableWhen
+	answers map[string][]models.QuestionnaireItemAnswer
+	// complete is set when required items must be answered, i.e. the response
+	// is not "in-progress"
+	complete bool
+	issues   []models.Issue
+}
+
+// validateResponse returns an issue for every unknown linkId, missing required
+// item, wrongly typed answer, disallowed answerOption and answer to an item
+// disabled by enableWhen. Required items are only enforced once the response
+// is no longer in progress.
+func validateResponse(q models.Questionnaire, resp models.QuestionnaireResponse) []models.Issue {
+	v := &responseValidator{
+		answers:  map[string][]models.QuestionnaireItemAnswer{},
+		complete: resp.Status != "in-progress",
+	}
+	indexAnswers(resp.Item, v.answers)
+
+	v.validateItems(q.Item, resp.Item, "")
+	return v.issues
+}
+
+// indexAnswers adds the answers with a value to the index, so that items with
+// only empty or null answers do not count as answered for "exists"
+func indexAnswers(items []models.QuestionnaireResponseItem, index map[string][]models.QuestionnaireItemAnswer) {
+	for _, item := range items {
+		if answers := withValues(item.Answer); len(answers) > 0 {
+			index[item.LinkID] = append(index[item.LinkID], answers...)
+		}
+		indexAnswers(item.Item, index)
+	}
+}
+
+// addIssue records an issue against a linkId path such as "falls-and-major-injury/FMI-02"
+func (v *responseValidator) addIssue(code, path, text string) {
+	v.issues = append(v.issues, models.Issue{
+		Severity:   "ERROR",
+		Code:       code,
+		Details:    &models.IssueDetails{Text: fmt.Sprintf("Item '%s': %s", path, text)},
+		Expression: []string{path},
+	})
+}
+
+// validateItems checks the response items at one level of nesting against the questionnaire items
+func (v *responseValidator) validateItems(questions []models.QuestionnaireItem, items []models.QuestionnaireResponseItem, parent string) {
+	known := map[string]bool{}
+	for _, question := range questions {
+		known[question.LinkID] = true
+	}
+
+	given := map[string]models.QuestionnaireResponseItem{}
+	for _, item := range items {
+		path := joinPath(parent, item.LinkID)
+		switch {
+		case !known[item.LinkID]:
+			v.addIssue("structure", path, "unknown linkId")
+		case given[item.LinkID].LinkID != "":
+			v.addIssue("structure", path, "linkId appears more than once")
+		default:
+			item.Answer = withValues(item.Answer)
+			given[item.LinkID] = item
+		}
+	}
+
+	for _, question := range questions {
+		path := joinPath(parent, question.LinkID)
+		item, answered := given[question.LinkID]
+
+		if !v.enabled(question) {
+			if answered && hasContent(item) {
+				v.addIssue("business-rule", path, "item is disabled by enableWhen and must not be answered")
+				// Answers to disabled items do not enable later items
+				v.forget([]models.QuestionnaireResponseItem{item})
+			}
+			continue
+		}
+
+		if !answered || !hasContent(item) {
+			if question.Required && v.complete {
+				v.addIssue("required", path, "required item is missing")
+			}
+			continue
+		}
+
+		v.validateItem(question, item, path)
+	}
+}
+
+// validateItem checks the answers and children of a single item
+func (v *responseValidator) validateItem(question models.QuestionnaireItem, item models.QuestionnaireResponseItem, path string) {
+	switch question.Type {
+	case "group":
+		if len(item.Answer) > 0 {
+			v.addIssue("structure", path, "group items cannot have answers")
+		}
+		v.validateItems(question.Item, item.Item, path)
+		return
+	case "display":
+		v.addIssue("structure", path, "display items cannot have answers")
+		return
+	}
+
+	if len(item.Item) > 0 {
+		v.addIssue("structure", path, "question items cannot have nested items")
+	}
+	if len(item.Answer) > 1 {
+		v.addIssue("structure", path, "only one answer is allowed")
+	}
+
+	for _, answer := range item.Answer {
+		got, ok := newValue(answer.ValueInteger, answer.ValueString, answer.ValueBoolean, answer.ValueDate)
+		if !ok {
+			v.addIssue("value", path, "answer must have only one of valueInteger, valueString, valueBoolean or valueDate")
+			continue
+		}
+		if got.kind == "date" && !datePattern.MatchString(got.s) {
+			v.addIssue("value", path, fmt.Sprintf("invalid date '%s', must be YYYY, YYYY-MM or YYYY-MM-DD", got.s))
+			continue
+		}
+		if want := expectedAnswerType(question); want != "" && got.kind != want {
+			v.addIssue("value", path, fmt.Sprintf("expected a value of type %s, got %s", want, got.kind))
+			continue
+		}
+		if len(question.AnswerOption) > 0 && !allowedOption(question.AnswerOption, got) {
+			v.addIssue("value", path, fmt.Sprintf("answer %s is not one of the allowed answerOptions", got))
+		}
+	}
+}
+
+// enabled evaluates an item's enableWhen conditions, all of which must hold
+// unless enableBehavior is "any"
+func (v *responseValidator) enabled(question models.QuestionnaireItem) bool {
+	if question.EnableBehavior == "any" && len(question.EnableWhen) > 0 {
+		for _, condition := range question.EnableWhen {
+			if v.conditionHolds(condition) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, condition := range question.EnableWhen {
+		if !v.conditionHolds(condition) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionHolds reports whether any answer to the condition's question satisfies it
+func (v *responseValidator) conditionHolds(condition models.EnableWhen) bool {
+	answers := v.answers[condition.Question]
+	if condition.Operator == "exists" {
+		return condition.AnswerBoolean != nil && (len(answers) > 0) == *condition.AnswerBoolean
+	}
+
+	want, ok := newValue(condition.AnswerInteger, condition.AnswerString, condition.AnswerBoolean, condition.AnswerDate)
+	if !ok {
+		return false
+	}
+	for _, answer := range answers {
+		got, ok := newValue(answer.ValueInteger, answer.ValueString, answer.ValueBoolean, answer.ValueDate)
+		if !ok || got.kind != want.kind {
+			continue
+		}
+
+		c := got.compare(want)
+		switch condition.Operator {
+		case "=":
+			if c == 0 {
+				return true
+			}
+		case "!=":
+			if c != 0 {
+				return true
+			}
+		case ">":
+			if c > 0 {
+				return true
+			}
+		case "<":
+			if c < 0 {
+				return true
+			}
+		case ">=":
+			if c >= 0 {
+				return true
+			}
+		case "<=":
+			if c <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forget removes the answers of items and their children from the enableWhen index
+func (v *responseValidator) forget(items []models.QuestionnaireResponseItem) {
+	for _, item := range items {
+		delete(v.answers, item.LinkID)
+		v.forget(item.Item)
+	}
+}
+
+// withValues drops answers without any value, such as {"valueString": null}
+// which the specification's examples send for empty comments
+func withValues(answers []models.QuestionnaireItemAnswer) []models.QuestionnaireItemAnswer {
+	kept := []models.QuestionnaireItemAnswer{}
+	for _, answer := range answers {
+		if answer.ValueInteger != nil || answer.ValueString != nil || answer.ValueBoolean != nil || answer.ValueDate != nil {
+			kept = append(kept, answer)
+		}
+	}
+	return kept
+}
+
+// hasContent reports whether a response item has any answers or nested items
+func hasContent(item models.QuestionnaireResponseItem) bool {
+	return len(item.Answer) > 0 || len(item.Item) > 0
+}
+
+// expectedAnswerType maps a questionnaire item type to the answer value type it
+// accepts. Choice items accept the type of their answerOptions.
+func expectedAnswerType(question models.QuestionnaireItem) string {
+	switch question.Type {
+	case "integer", "boolean", "string", "date":
+		return question.Type
+	case "text":
+		return "string"
+	case "choice":
+		if len(question.AnswerOption) > 0 {
+			option := question.AnswerOption[0]
+			if value, ok := newValue(option.ValueInteger, option.ValueString, option.ValueBoolean, option.ValueDate); ok {
+				return value.kind
+			}
+		}
+	}
+	return ""
+}
+
+func allowedOption(options []models.AnswerOption, answer value) bool {
+	for _, option := range options {
+		allowed, ok := newValue(option.ValueInteger, option.ValueString, option.ValueBoolean, option.ValueDate)
+		if ok && allowed.kind == answer.kind && allowed.compare(answer) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// value is a typed answer, answerOption or enableWhen value
+type value struct {
+	kind string // integer, string, boolean or date
+	i    int
+	s    string // string and date values
+	b    bool
+}
+
+// newValue returns the single value set out of an integer, string, boolean and date
+func newValue(i *int, s *string, b *bool, d *string) (value, bool) {
+	var values []value
+	if i != nil {
+		values = append(values, value{kind: "integer", i: *i})
+	}
+	if s != nil {
+		values = append(values, value{kind: "string", s: *s})
+	}
+	if b != nil {
+		values = append(values, value{kind: "boolean", b: *b})
+	}
+	if d != nil {
+		values = append(values, value{kind: "date", s: *d})
+	}
+	if len(values) != 1 {
+		return value{}, false
+	}
+	return values[0], true
+}
+
+// compare orders two values of the same kind; false sorts before true
+func (a value) compare(b value) int {
+	switch a.kind {
+	case "integer":
+		return a.i - b.i
+	case "string", "date":
+		return strings.Compare(a.s, b.s)
+	case "boolean":
+		if a.b == b.b {
+			return 0
+		}
+		if a.b {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+func (a value) String() string {
+	switch a.kind {
+	case "string", "date":
+		return fmt.Sprintf("'%s'", a.s)
+	case "boolean":
+		return fmt.Sprint(a.b)
+	}
+	return fmt.Sprint(a.i)
+}
+
+func joinPath(parent, linkID string) string {
+	if parent == "" {
+		return linkID
+	}
+	return parent + "/" + linkID
+}
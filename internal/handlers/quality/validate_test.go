@@ -0,0 +1,78 @@
+package quality
+
+import (
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// enableWhenQuestionnaire has a question "detail" that is enabled by its conditions
+func enableWhenQuestionnaire(behavior string, conditions ...models.EnableWhen) models.Questionnaire {
+	return models.Questionnaire{
+		ID: "QC-TEST",
+		Item: []models.QuestionnaireItem{
+			{LinkID: "count", Type: "integer"},
+			{LinkID: "comment", Type: "string"},
+			{LinkID: "detail", Type: "string", EnableWhen: conditions, EnableBehavior: behavior},
+		},
+	}
+}
+
+func answered(linkID string, answers ...models.QuestionnaireItemAnswer) models.QuestionnaireResponseItem {
+	return models.QuestionnaireResponseItem{LinkID: linkID, Answer: answers}
+}
+
+var detailAnswer = answered("detail", models.QuestionnaireItemAnswer{ValueString: stringPtr("more")})
+
+func TestEnableWhenExists(t *testing.T) {
+	q := enableWhenQuestionnaire("", models.EnableWhen{Question: "comment", Operator: "exists", AnswerBoolean: boolPtr(true)})
+
+	tests := []struct {
+		name    string
+		comment []models.QuestionnaireResponseItem
+		enabled bool
+	}{
+		{"answered", []models.QuestionnaireResponseItem{answered("comment", models.QuestionnaireItemAnswer{ValueString: stringPtr("x")})}, true},
+		{"not given", nil, false},
+		{"no answers", []models.QuestionnaireResponseItem{answered("comment")}, false},
+		{"null answer", []models.QuestionnaireResponseItem{answered("comment", models.QuestionnaireItemAnswer{})}, false},
+	}
+	for _, tt := range tests {
+		resp := models.QuestionnaireResponse{Status: "in-progress", Item: append(tt.comment, detailAnswer)}
+		issues := validateResponse(q, resp)
+		if enabled := len(issues) == 0; enabled != tt.enabled {
+			t.Errorf("%s: detail enabled %v (%v), want %v", tt.name, enabled, issues, tt.enabled)
+		}
+	}
+}
+
+func TestEnableBehavior(t *testing.T) {
+	conditions := []models.EnableWhen{
+		{Question: "count", Operator: ">", AnswerInteger: intPtr(0)},
+		{Question: "comment", Operator: "exists", AnswerBoolean: boolPtr(true)},
+	}
+	count := answered("count", models.QuestionnaireItemAnswer{ValueInteger: intPtr(2)})
+	noCount := answered("count", models.QuestionnaireItemAnswer{ValueInteger: intPtr(0)})
+	comment := answered("comment", models.QuestionnaireItemAnswer{ValueString: stringPtr("x")})
+
+	tests := []struct {
+		behavior string
+		items    []models.QuestionnaireResponseItem
+		enabled  bool
+	}{
+		{"", []models.QuestionnaireResponseItem{count, comment}, true},
+		{"", []models.QuestionnaireResponseItem{count}, false},
+		{"all", []models.QuestionnaireResponseItem{count}, false},
+		{"any", []models.QuestionnaireResponseItem{count}, true},
+		{"any", []models.QuestionnaireResponseItem{noCount, comment}, true},
+		{"any", []models.QuestionnaireResponseItem{noCount}, false},
+	}
+	for _, tt := range tests {
+		q := enableWhenQuestionnaire(tt.behavior, conditions...)
+		resp := models.QuestionnaireResponse{Status: "in-progress", Item: append(tt.items, detailAnswer)}
+		issues := validateResponse(q, resp)
+		if enabled := len(issues) == 0; enabled != tt.enabled {
+			t.Errorf("enableBehavior %q with %d items: detail enabled %v (%v), want %v", tt.behavior, len(tt.items), enabled, issues, tt.enabled)
+		}
+	}
+}
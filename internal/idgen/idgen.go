@@ -0,0 +1,103 @@
+package idgen
+
+import (
+	crand "crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// firstSeq is the first sequence number issued for each ID prefix, above the
+// IDs used by the mock data
+var firstSeq = map[string]int{
+	"QR":  100001,
+	"SD":  20001,
+	"RNU": 5001,
+}
+
+// Generator issues unique IDs. Prefixed resource IDs use a sequence per
+// prefix; UUIDs are random, or reproducible when the generator is seeded.
+// It is safe for concurrent use.
+type Generator struct {
+	mu     sync.Mutex
+	seqs   map[string]int
+	random io.Reader
+}
+
+// New returns a generator drawing UUIDs from crypto/rand
+func New() *Generator {
+	return &Generator{seqs: map[string]int{}, random: crand.Reader}
+}
+
+// NewSeeded returns a generator whose UUIDs are reproducible for a seed
+func NewSeeded(seed int64) *Generator {
+	return &Generator{seqs: map[string]int{}, random: rand.New(rand.NewSource(seed))}
+}
+
+// FromEnv returns a generator seeded with MOCK_ID_SEED, or an unseeded one
+// when it is not set
+func FromEnv() *Generator {
+	value := os.Getenv("MOCK_ID_SEED")
+	if value == "" {
+		return New()
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid MOCK_ID_SEED '%s': %v", value, err)
+		return New()
+	}
+	log.Printf("ID generator seeded with %d", seed)
+	return NewSeeded(seed)
+}
+
+// UUID returns a version 4 UUID, as used for OAuth client IDs
+func (g *Generator) UUID() string {
+	var b [16]byte
+	g.mu.Lock()
+	_, err := io.ReadFull(g.random, b[:])
+	g.mu.Unlock()
+	if err != nil {
+		panic(fmt.Sprintf("idgen: reading random bytes: %v", err))
+	}
+
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// QuestionnaireResponse returns a new QuestionnaireResponse ID, e.g. "QR-100001"
+func (g *Generator) QuestionnaireResponse() string {
+	return fmt.Sprintf("QR-%d", g.next("QR"))
+}
+
+// SubmissionDay returns a new attendance day ID for a submission, sharing its
+// date part, e.g. "SD-240708-20001" for "Sub-240708-504"
+func (g *Generator) SubmissionDay(submissionID string) string {
+	date := ""
+	if parts := strings.Split(submissionID, "-"); len(parts) > 1 {
+		date = parts[1]
+	}
+	return fmt.Sprintf("SD-%s-%d", date, g.next("SD"))
+}
+
+// NonAttendance returns a new non-attendance time ID, e.g. "RNU-5001"
+func (g *Generator) NonAttendance() string {
+	return fmt.Sprintf("RNU-%d", g.next("RNU"))
+}
+
+// next returns the next sequence number for a prefix
+func (g *Generator) next(prefix string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	seq, ok := g.seqs[prefix]
+	if !ok {
+		seq = firstSeq[prefix]
+	}
+	g.seqs[prefix] = seq + 1
+	return seq
+}
@@ -0,0 +1,87 @@
+package idgen
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// draw returns n of each kind of ID from g
+func draw(g *Generator, n int) []string {
+	var ids []string
+	for i := 0; i < n; i++ {
+		ids = append(ids, g.UUID(), g.QuestionnaireResponse(), g.SubmissionDay("Sub-240708-504"), g.NonAttendance())
+	}
+	return ids
+}
+
+func TestSeededIsReproducible(t *testing.T) {
+	first, second := draw(NewSeeded(42), 50), draw(NewSeeded(42), 50)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("ID %d: got %s and %s from the same seed", i, first[i], second[i])
+		}
+	}
+
+	if other := NewSeeded(43).UUID(); other == first[0] {
+		t.Errorf("seeds 42 and 43 both gave UUID %s", other)
+	}
+}
+
+func TestFromEnvSeed(t *testing.T) {
+	t.Setenv("MOCK_ID_SEED", "42")
+	if got, want := FromEnv().UUID(), NewSeeded(42).UUID(); got != want {
+		t.Errorf("MOCK_ID_SEED=42 gave UUID %s, want %s", got, want)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	g := New()
+	if id := g.UUID(); !uuidPattern.MatchString(id) {
+		t.Errorf("UUID() = %s, not a version 4 UUID", id)
+	}
+
+	tests := []struct{ got, want string }{
+		{g.QuestionnaireResponse(), "QR-100001"},
+		{g.QuestionnaireResponse(), "QR-100002"},
+		{g.SubmissionDay("Sub-240708-504"), "SD-240708-20001"},
+		{g.NonAttendance(), "RNU-5001"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
+
+func TestConcurrentIDsAreUnique(t *testing.T) {
+	for _, g := range []*Generator{New(), NewSeeded(42)} {
+		const workers, perWorker = 20, 100
+		results := make([][]string, workers)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				results[w] = draw(g, perWorker)
+			}(w)
+		}
+		wg.Wait()
+
+		seen := map[string]bool{}
+		for _, ids := range results {
+			for _, id := range ids {
+				if seen[id] {
+					t.Errorf("%s issued twice", id)
+				}
+				seen[id] = true
+			}
+		}
+		if want := workers * perWorker * 4; len(seen) != want {
+			t.Errorf("got %d unique IDs, want %d", len(seen), want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package journal
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+)
+
+// MaxBodySize is the number of body bytes kept for each request and response
+const MaxBodySize = 64 * 1024
+
+// Journal of the most recent API calls, oldest first
+var (
+	mu      sync.Mutex
+	entries []models.JournalEntry
+	// size is the number of entries kept; 0 turns the journal off
+	size   int
+	nextID = 1
+)
+
+// LoadFromEnv sets the journal size from MOCK_JOURNAL_SIZE. The journal is off
+// unless it is set above 0.
+func LoadFromEnv() {
+	value := os.Getenv("MOCK_JOURNAL_SIZE")
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Ignoring invalid MOCK_JOURNAL_SIZE '%s'", value)
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	size = n
+	trim()
+}
+
+// Enabled reports whether calls are being recorded
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return size > 0
+}
+
+// Add records an entry, assigning its ID and dropping the oldest entries
+// beyond the journal size
+func Add(entry models.JournalEntry) {
+	mu.Lock()
+	defer mu.Unlock()
+	if size == 0 {
+		return
+	}
+	entry.ID = nextID
+	nextID++
+	entries = append(entries, entry)
+	trim()
+}
+
+// trim drops the oldest entries beyond the journal size. Callers must hold mu.
+func trim() {
+	if len(entries) > size {
+		entries = append([]models.JournalEntry(nil), entries[len(entries)-size:]...)
+	}
+}
+
+// Filter selects journal entries; zero fields match everything
+type Filter struct {
+	Method        string
+	Route         string
+	Status        int
+	TransactionID string
+	Since         time.Time
+	// Limit keeps only the most recent matching entries
+	Limit int
+}
+
+// List returns the entries matching a filter, oldest first
+func List(f Filter) []models.JournalEntry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	matched := []models.JournalEntry{}
+	for _, entry := range entries {
+		if f.Method != "" && entry.Request.Method != f.Method {
+			continue
+		}
+		if f.Route != "" && !routematch.Match(f.Route, entry.Route) {
+			continue
+		}
+		if f.Status != 0 && entry.Response.Status != f.Status {
+			continue
+		}
+		if f.TransactionID != "" && entry.TransactionID != f.TransactionID {
+			continue
+		}
+		if !f.Since.IsZero() && entry.Time.Before(f.Since) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+	return matched
+}
+
+// Get returns an entry by ID
+func Get(id int) (models.JournalEntry, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return models.JournalEntry{}, false
+}
+
+// Clear removes every entry
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	entries = nil
+}
@@ -5,43 +5,65 @@ import (
 	"strings"
 
 	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/tokens"
 )
 
 // AuthMiddleware is a simplified JWT authentication middleware
-// In a real application, this would validate JWT tokens properly
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth for the authentication endpoints
-		if strings.HasPrefix(r.URL.Path, "/oauth2") {
+// In a real application, this would validate JWT tokens properly.
+// Token expiry is checked against clk.
+func AuthMiddleware(clk clock.Clock) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Skip auth for the authentication endpoints
+			if strings.HasPrefix(r.URL.Path, "/oauth2") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Get the authorization header
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Authorization header is required"})
+				return
+			}
+
+			// Check if it's a Bearer token
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Authorization header must be Bearer token"})
+				return
+			}
+
+			// For mock API, we'll accept any token that starts with "mock_"
+			token := parts[1]
+			if !strings.HasPrefix(token, "mock_") {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Invalid token"})
+				return
+			}
+
+			// Tokens issued by the token endpoint carry the organisations they are bound to.
+			// Expired tokens are evicted, and evicted or revoked ones stay rejected.
+			// Other "mock_" tokens remain unrestricted.
+			if claims, ok := tokens.Lookup(token); ok {
+				if !claims.ExpiresAt.IsZero() && !clk.Now().Before(claims.ExpiresAt) {
+					tokens.Delete(token)
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, map[string]string{"error": "Token has expired"})
+					return
+				}
+				r = r.WithContext(tokens.WithClaims(r.Context(), claims))
+			} else if tokens.Issued(token) {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, map[string]string{"error": "Token has expired or been revoked"})
+				return
+			}
+
+			// Pass request to the next handler
 			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Get the authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Authorization header is required"})
-			return
-		}
-
-		// Check if it's a Bearer token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Authorization header must be Bearer token"})
-			return
-		}
-
-		// For mock API, we'll accept any token that starts with "mock_"
-		token := parts[1]
-		if !strings.HasPrefix(token, "mock_") {
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, map[string]string{"error": "Invalid token"})
-			return
-		}
-
-		// Pass request to the next handler
-		next.ServeHTTP(w, r)
-	})
+		})
+	}
 }
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/faults"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+)
+
+// FaultInjection injects the latency, error responses, dropped connections and
+// malformed bodies configured in the faults store. The admin endpoints are
+// never faulted, so that rules can always be removed.
+func FaultInjection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routematch.Path(r)
+		if strings.HasPrefix(route, "/admin") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule, ok := faults.Next(r.Method, route)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log.Printf("Injecting fault %s into %s %s", rule.ID, r.Method, r.URL.Path)
+
+		if rule.Latency != "" {
+			delay, _ := time.ParseDuration(rule.Latency)
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case rule.Drop:
+			// Abort the connection without writing a response
+			panic(http.ErrAbortHandler)
+		case rule.Status != 0:
+			if rule.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(rule.RetryAfter))
+			}
+			code := "transient"
+			if rule.Status == http.StatusTooManyRequests {
+				code = "throttled"
+			}
+			render.Status(r, rule.Status)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", code, fmt.Sprintf("Injected fault %s: %d %s", rule.ID, rule.Status, http.StatusText(rule.Status))))
+		case rule.Malformed:
+			// Run the handler, then send only the first half of its body
+			rec := &bufferedWriter{header: http.Header{}, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			for key, values := range rec.header {
+				w.Header()[key] = values
+			}
+			w.Header().Del("Content-Length")
+			w.WriteHeader(rec.status)
+			body := rec.body.Bytes()
+			w.Write(body[:len(body)/2])
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// bufferedWriter captures a handler's response
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header         { return b.header }
+func (b *bufferedWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedWriter) WriteHeader(status int)      { b.status = status }
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// FederatedIDHeader carries the email of the end user behind a call
+const FederatedIDHeader = "X-Federated-Id"
+
+// federatedIDPattern is the X-Federated-Id pattern from the API specs
+var federatedIDPattern = regexp.MustCompile(`^\S+@\S+\.\S+$`)
+
+// FederatedID rejects calls whose optional X-Federated-Id header is not an
+// email address. Handlers record the header as the author or modifier of the
+// resources they change.
+func FederatedID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get(FederatedIDHeader); id != "" && !federatedIDPattern.MatchString(id) {
+			log.Printf("Rejected %s %s with invalid X-Federated-Id '%s'", r.Method, r.URL.Path, id)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid X-Federated-Id '"+id+"', must match "+federatedIDPattern.String()))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
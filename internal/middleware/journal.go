@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/journal"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/redact"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+)
+
+// Journal records every API call and its response in the request journal,
+// timestamped with clk, with tokens and secrets redacted. Calls to the admin
+// endpoints are not recorded.
+func Journal(clk clock.Clock) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routematch.Path(r)
+			if !journal.Enabled() || strings.HasPrefix(route, "/admin") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Keep the body for the handler after copying it
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			request := models.JournalRequest{
+				Method:  r.Method,
+				Path:    r.URL.Path,
+				Query:   redact.Query(r.URL.RawQuery),
+				Headers: redact.Headers(r.Header),
+			}
+			request.Body, request.BodyTruncated = truncateBody(redact.Body(r.Header.Get("Content-Type"), string(body)))
+
+			// The whole response is kept so that it is redacted before it is truncated
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			var responseBody bytes.Buffer
+			ww.Tee(&responseBody)
+
+			at := clk.Now()
+			started := time.Now()
+
+			// Record the call even when the handler aborts the connection
+			defer func() {
+				response := models.JournalResponse{
+					Status:  ww.Status(),
+					Headers: redact.Headers(ww.Header()),
+				}
+				response.Body, response.BodyTruncated = truncateBody(redact.Body(ww.Header().Get("Content-Type"), responseBody.String()))
+				journal.Add(models.JournalEntry{
+					Time:          at,
+					Route:         route,
+					DurationMs:    float64(time.Since(started).Microseconds()) / 1000,
+					TransactionID: r.Header.Get(TransactionIDHeader),
+					Request:       request,
+					Response:      response,
+				})
+			}()
+
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+func truncateBody(body string) (string, bool) {
+	if len(body) > journal.MaxBodySize {
+		return body[:journal.MaxBodySize], true
+	}
+	return body, false
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/journal"
+	"github.com/jasonchiu/dohac-mock-apis/internal/redact"
+)
+
+// journalled sends a request through the journal to a handler writing body,
+// returning the recorded entry's response body and whether it was truncated
+func journalled(t *testing.T, body string) (string, bool) {
+	t.Helper()
+	t.Setenv("MOCK_JOURNAL_SIZE", "10")
+	journal.LoadFromEnv()
+	journal.Clear()
+	defer journal.Clear()
+
+	handler := Journal(clock.NewVirtual())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/oauth2/access-tokens", strings.NewReader("grant_type=client_credentials")))
+
+	entries := journal.List(journal.Filter{})
+	if len(entries) != 1 {
+		t.Fatalf("got %d journal entries, want 1", len(entries))
+	}
+	return entries[0].Response.Body, entries[0].Response.BodyTruncated
+}
+
+func TestJournalRedactsTruncatedTokenResponse(t *testing.T) {
+	// The token is in the kept bytes, but the JSON is only complete after them
+	padding := strings.Repeat("x", journal.MaxBodySize)
+	body, truncated := journalled(t, `{"access_token":"secret-token","token_type":"Bearer","scope":"`+padding+`"}`)
+
+	if !truncated || len(body) != journal.MaxBodySize {
+		t.Errorf("got %d bytes, truncated %v, want %d truncated bytes", len(body), truncated, journal.MaxBodySize)
+	}
+	if strings.Contains(body, "secret-token") {
+		t.Error("journal kept the access token")
+	}
+}
+
+func TestJournalRedactsTokenResponse(t *testing.T) {
+	tests := []struct {
+		name, body string
+	}{
+		{"complete", `{"access_token":"secret-token","token_type":"Bearer","expires_in":3600}`},
+		// Such as a response cut off by a malformed fault
+		{"cut off", `{"access_token":"secret-token","token_ty`},
+	}
+	for _, tt := range tests {
+		body, truncated := journalled(t, tt.body)
+		if truncated || strings.Contains(body, "secret-token") || !strings.Contains(body, redact.Redacted) {
+			t.Errorf("%s: got body %s, truncated %v, want the token redacted", tt.name, body, truncated)
+		}
+	}
+}
+
+func TestJournalKeepsOtherResponses(t *testing.T) {
+	for _, want := range []string{`{"resourceType":"Bundle","total":0}`, `{"resourceType":"Bund`} {
+		if body, _ := journalled(t, want); body != want {
+			t.Errorf("got body %s, want %s", body, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+	"github.com/jasonchiu/dohac-mock-apis/internal/scenarios"
+)
+
+// Scenarios returns the scripted responses of the scenario named in the
+// X-Mock-Scenario header, or of the active scenario. Requests matching no
+// step, or a passthrough response, reach the API as usual.
+func Scenarios(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(scenarios.Header)
+		if name == "" {
+			name = scenarios.Active()
+		}
+		route := routematch.Path(r)
+		if name == "" || strings.HasPrefix(route, "/admin") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Keep the body for the handler after matching on it
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Could not read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		resp, ok, err := scenarios.Respond(name, r, route, body)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found", err.Error()))
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Printf("Scenario %s: scripted %d response for %s %s", name, resp.Status, r.Method, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		for key, value := range resp.Headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(resp.Status)
+		switch body := resp.Body.(type) {
+		case nil:
+		case string:
+			io.WriteString(w, body)
+		default:
+			json.NewEncoder(w).Encode(body)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/idgen"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// TransactionIDHeader correlates a call across the client, the API and its logs
+const TransactionIDHeader = "transaction_id"
+
+// transactionIDPattern is the transaction_id pattern from the API specs
+var transactionIDPattern = regexp.MustCompile(`^[a-zA-Z0-9\-\_]*$`)
+
+// transactionIDs generates the missing transaction IDs. It is separate from the
+// handlers' generator, so that calls without the header do not move the
+// MOCK_ID_SEED sequence of client IDs and tokens.
+var transactionIDs = idgen.New()
+
+// TransactionID validates the transaction_id header, generating one when it
+// is absent. The ID is set on the request for the handlers and the journal,
+// echoed on the response, used as the request ID in the access log and added
+// to the diagnostics of OperationOutcome responses.
+func TransactionID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(TransactionIDHeader)
+		if !transactionIDPattern.MatchString(id) {
+			log.Printf("Rejected %s %s with invalid transaction_id '%s'", r.Method, r.URL.Path, id)
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, models.NewOperationOutcome("ERROR", "invalid", "Invalid transaction_id, must match "+transactionIDPattern.String()))
+			return
+		}
+		if id == "" {
+			id = transactionIDs.UUID()
+			r.Header.Set(TransactionIDHeader, id)
+		}
+		w.Header().Set(TransactionIDHeader, id)
+		r = r.WithContext(context.WithValue(r.Context(), chimiddleware.RequestIDKey, id))
+
+		ow := &outcomeWriter{ResponseWriter: w}
+		next.ServeHTTP(ow, r)
+		ow.finish(id)
+	})
+}
+
+// outcomeWriter holds back error responses with a JSON body, which may be
+// OperationOutcomes needing the transaction ID in their diagnostics. Other
+// responses are written, and flushed, straight through.
+type outcomeWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	status      int
+	// held is set when the response is being buffered into body
+	held bool
+	body bytes.Buffer
+}
+
+func (w *outcomeWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if status >= http.StatusBadRequest && strings.Contains(w.Header().Get("Content-Type"), "json") {
+		w.held = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *outcomeWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.held {
+		return w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *outcomeWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.held {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *outcomeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish writes a held error response, adding the transaction ID to it if it
+// is an OperationOutcome
+func (w *outcomeWriter) finish(id string) {
+	if !w.held {
+		return
+	}
+	body := w.body.Bytes()
+	if bytes.HasPrefix(body, []byte(`{"resourceType":"OperationOutcome"`)) {
+		body = withDiagnostics(body, id)
+		w.Header().Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(body)
+}
+
+// withDiagnostics adds the transaction ID to the diagnostics of each issue in
+// an encoded OperationOutcome
+func withDiagnostics(body []byte, id string) []byte {
+	var outcome models.OperationOutcome
+	if err := json.Unmarshal(body, &outcome); err != nil {
+		return body
+	}
+	for i := range outcome.Issue {
+		issue := &outcome.Issue[i]
+		if issue.Diagnostics == "" {
+			issue.Diagnostics = "transaction_id: " + id
+		} else {
+			issue.Diagnostics += " (transaction_id: " + id + ")"
+		}
+	}
+	encoded, err := json.Marshal(outcome)
+	if err != nil {
+		return body
+	}
+	// Keep the trailing newline written by render.JSON
+	return append(encoded, '\n')
+}
@@ -0,0 +1,165 @@
+package models
+
+import "time"
+
+// Admin models for controlling the mock server
+
+// ClockState reports the mock server's virtual clock
+type ClockState struct {
+	Now    time.Time `json:"now"`
+	Frozen bool      `json:"frozen"`
+}
+
+// ClockRequest sets or advances the virtual clock. Now is an RFC 3339 time or
+// a YYYY-MM-DD date; Advance is a Go duration such as "72h" or "-30m".
+type ClockRequest struct {
+	Now     string `json:"now,omitempty"`
+	Frozen  *bool  `json:"frozen,omitempty"`
+	Advance string `json:"advance,omitempty"`
+}
+
+// FaultRule injects a fault into the API calls matching a route pattern. Route
+// uses chi pattern syntax relative to /api, e.g. "/QuestionnaireResponse/{id}"
+// or "/Provider/*". A rule fires on every Nth matching call when Nth is set,
+// otherwise with the given Probability (always when nil, never when 0), at
+// most Times times when Times is set.
+type FaultRule struct {
+	ID          string   `json:"id,omitempty"`
+	Method      string   `json:"method,omitempty"`
+	Route       string   `json:"route"`
+	Latency     string   `json:"latency,omitempty"`
+	Status      int      `json:"status,omitempty"`
+	RetryAfter  int      `json:"retryAfter,omitempty"`
+	Drop        bool     `json:"drop,omitempty"`
+	Malformed   bool     `json:"malformed,omitempty"`
+	Probability *float64 `json:"probability,omitempty"`
+	Nth         int      `json:"nth,omitempty"`
+	Times       int      `json:"times,omitempty"`
+	// Calls and Injected count the matching calls and injected faults
+	Calls    int `json:"calls"`
+	Injected int `json:"injected"`
+}
+
+// Scenario is a named script of responses for the requests matching its steps
+type Scenario struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Steps       []ScenarioStep `json:"steps"`
+	// Active is set on the scenario applied to requests without an X-Mock-Scenario header
+	Active bool `json:"active"`
+}
+
+// ScenarioStep returns its responses in order to successive matching requests;
+// the last response is repeated once the others have been used
+type ScenarioStep struct {
+	Match     RequestMatcher     `json:"match"`
+	Responses []ScenarioResponse `json:"responses"`
+	// Calls counts the requests matched by the step
+	Calls int `json:"calls"`
+}
+
+// RequestMatcher selects requests. Path uses chi pattern syntax relative to
+// /api. Headers and Query must have the given values; Body matches fields of a
+// JSON body (dotted paths for nested fields) or form fields.
+type RequestMatcher struct {
+	Method       string            `json:"method,omitempty"`
+	Path         string            `json:"path"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Query        map[string]string `json:"query,omitempty"`
+	Body         map[string]string `json:"body,omitempty"`
+	BodyContains string            `json:"bodyContains,omitempty"`
+}
+
+// ScenarioResponse is a scripted response, or Passthrough to let the API respond
+type ScenarioResponse struct {
+	Status      int               `json:"status,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        any               `json:"body,omitempty"`
+	Passthrough bool              `json:"passthrough,omitempty"`
+}
+
+// ScenarioActivation names the scenario to activate
+type ScenarioActivation struct {
+	Name string `json:"name"`
+}
+
+// JournalEntry records an API call and its response
+type JournalEntry struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// Route is the path relative to /api
+	Route         string          `json:"route"`
+	DurationMs    float64         `json:"durationMs"`
+	TransactionID string          `json:"transactionId,omitempty"`
+	Request       JournalRequest  `json:"request"`
+	Response      JournalResponse `json:"response"`
+}
+
+// JournalRequest is the request part of a journal entry
+type JournalRequest struct {
+	Method        string              `json:"method"`
+	Path          string              `json:"path"`
+	Query         string              `json:"query,omitempty"`
+	Headers       map[string][]string `json:"headers"`
+	Body          string              `json:"body,omitempty"`
+	BodyTruncated bool                `json:"bodyTruncated,omitempty"`
+}
+
+// JournalResponse is the response part of a journal entry. Status is 0 when
+// the connection was dropped without a response.
+type JournalResponse struct {
+	Status        int                 `json:"status"`
+	Headers       map[string][]string `json:"headers"`
+	Body          string              `json:"body,omitempty"`
+	BodyTruncated bool                `json:"bodyTruncated,omitempty"`
+}
+
+// Recording is a request and response pair recorded from the upstream in
+// record mode and served in replay mode, with tokens and secrets redacted
+type Recording struct {
+	RecordedAt time.Time       `json:"recordedAt"`
+	Route      string          `json:"route"`
+	Request    JournalRequest  `json:"request"`
+	Response   JournalResponse `json:"response"`
+}
+
+// Webhook is an outbound subscription to mock events. Payloads are signed
+// with Secret; failed deliveries are retried up to MaxAttempts times, waiting
+// Backoff (doubled after each attempt, up to a minute) in between.
+type Webhook struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	// Events to deliver; empty means every event
+	Events      []string `json:"events,omitempty"`
+	MaxAttempts int      `json:"maxAttempts,omitempty"`
+	Backoff     string   `json:"backoff,omitempty"`
+}
+
+// WebhookEvent is the payload posted to webhooks
+type WebhookEvent struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// WebhookDelivery records the delivery of an event to a webhook. Status is
+// "pending" while attempts remain, then "delivered" or "failed".
+type WebhookDelivery struct {
+	ID        int              `json:"id"`
+	WebhookID string           `json:"webhookId"`
+	URL       string           `json:"url"`
+	Event     WebhookEvent     `json:"event"`
+	Status    string           `json:"status"`
+	Attempts  []WebhookAttempt `json:"attempts"`
+}
+
+// WebhookAttempt is one try at delivering an event. StatusCode is 0 when no
+// response was received.
+type WebhookAttempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+}
@@ -33,8 +33,14 @@ type BundleLink struct {
 
 // BundleEntry represents an entry in a FHIR bundle
 type BundleEntry struct {
-	FullURL  string `json:"fullUrl"`
-	Resource any    `json:"resource"`
+	FullURL  string             `json:"fullUrl"`
+	Resource any                `json:"resource"`
+	Search   *BundleEntrySearch `json:"search,omitempty"`
+}
+
+// BundleEntrySearch records why an entry is in a searchset bundle ("match" or "include")
+type BundleEntrySearch struct {
+	Mode string `json:"mode"`
 }
 
 // Annotation represents a text note
@@ -48,17 +54,31 @@ type Period struct {
 	End   time.Time `json:"end,omitempty"`
 }
 
-// AttendanceSummary represents a summary of registered nurse attendance
+// AttendanceSummary represents the computed attendance summary for a monthly submission
 type AttendanceSummary struct {
-	ID             string    `json:"id"`
-	ServiceID      string    `json:"serviceId"`
-	ServiceName    string    `json:"serviceName"`
-	Date           time.Time `json:"date"`
-	TotalHours     float64   `json:"totalHours"`
-	DailyRequired  bool      `json:"dailyRequired"`
-	CompliantDay   bool      `json:"compliantDay"`
-	CompliantWeek  bool      `json:"compliantWeek"`
-	CompliantMonth bool      `json:"compliantMonth"`
+	ResourceType                    string                     `json:"resourceType"`
+	ID                              string                     `json:"id"`
+	NominatedServiceIdentifier      NominatedServiceIdentifier `json:"nominatedServiceIdentifier"`
+	SubmissionStatus                string                     `json:"submissionStatus"`
+	ReportingPeriod                 ReportingPeriodPatch       `json:"reportingPeriod"`
+	TotalCoverageHours              float64                    `json:"totalCoverageHours"`
+	TotalUnavailableHours           float64                    `json:"totalUnavailableHours"`
+	TotalHoursWithoutAltArrangement float64                    `json:"totalHoursWithoutAltArrangement"`
+	CoveragePercentage              float64                    `json:"coveragePercentage"`
+	CompliantDay                    bool                       `json:"compliantDay"`
+	CompliantWeek                   bool                       `json:"compliantWeek"`
+	CompliantMonth                  bool                       `json:"compliantMonth"`
+	Weeks                           []WeekSummary              `json:"weeks,omitempty"`
+}
+
+// WeekSummary is the compliance of one ISO week (Monday to Sunday) of a
+// reporting period; the first and last weeks are cut at the period boundaries
+type WeekSummary struct {
+	Start                           string  `json:"start"`
+	End                             string  `json:"end"`
+	Reported                        bool    `json:"reported"`
+	TotalHoursWithoutAltArrangement float64 `json:"totalHoursWithoutAltArrangement"`
+	Compliant                       bool    `json:"compliant"`
 }
 
 // New structs for PATCH /RegisteredNurseAttendance/{id} payload
@@ -116,4 +136,14 @@ type RegisteredNurseAttendancePatchPayload struct {
 	TotalUnavailableHours           *float64                   `json:"totalUnavailableHours,omitempty"`
 	TotalHoursWithoutAltArrangement *float64                   `json:"totalHoursWithoutAltArrangement,omitempty"`
 	CoveragePercentage              *float64                   `json:"coveragePercentage,omitempty"`
-}
\ No newline at end of file
+	// End users from the X-Federated-Id header: the last to change the
+	// submission, and the one who submitted it with the reporter declaration
+	LastModifiedBy string `json:"lastModifiedBy,omitempty"`
+	SubmittedBy    string `json:"submittedBy,omitempty"`
+}
+
+// SubmissionStatusChange is the data of a submission status webhook event
+type SubmissionStatusChange struct {
+	PreviousStatus string                                `json:"previousStatus"`
+	Submission     RegisteredNurseAttendancePatchPayload `json:"submission"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// OperationOutcome represents a FHIR OperationOutcome used for error responses
+type OperationOutcome struct {
+	ResourceType string  `json:"resourceType"`
+	Issue        []Issue `json:"issue"`
+}
+
+// Issue represents a single issue in an OperationOutcome
+type Issue struct {
+	Severity    string        `json:"severity"`
+	Code        string        `json:"code"`
+	Details     *IssueDetails `json:"details,omitempty"`
+	Diagnostics string        `json:"diagnostics,omitempty"`
+	Expression  []string      `json:"expression,omitempty"`
+}
+
+// IssueDetails holds the plain text description of an issue
+type IssueDetails struct {
+	Text string `json:"text"`
+}
+
+// NewOperationOutcome creates an OperationOutcome with a single issue
+func NewOperationOutcome(severity, code, text string) OperationOutcome {
+	return OperationOutcome{
+		ResourceType: "OperationOutcome",
+		Issue: []Issue{
+			{
+				Severity: severity,
+				Code:     code,
+				Details:  &IssueDetails{Text: text},
+			},
+		},
+	}
+}
@@ -1,9 +1,12 @@
 package models
 
+import "time"
+
 // Provider represents a healthcare provider
 type Provider struct {
 	ID           string            `json:"id"`
 	ResourceType string            `json:"resourceType"`
+	Meta         *Meta             `json:"meta,omitempty"`
 	Identifier   []Identifier      `json:"identifier"`
 	Active       bool              `json:"active"`
 	Type         []CodeableConcept `json:"type"`
@@ -17,6 +20,7 @@ type Provider struct {
 type HealthcareService struct {
 	ID                   string            `json:"id"`
 	ResourceType         string            `json:"resourceType"`
+	Meta                 *Meta             `json:"meta,omitempty"`
 	Identifier           []Identifier      `json:"identifier"`
 	Active               bool              `json:"active"`
 	ProvidedBy           Reference         `json:"providedBy"`
@@ -29,6 +33,14 @@ type HealthcareService struct {
 }
 
 // Common FHIR resource elements
+
+// Meta holds resource metadata. Source identifies the call that last changed
+// the resource, as "#" and its transaction_id.
+type Meta struct {
+	LastUpdated time.Time `json:"lastUpdated"`
+	Source      string    `json:"source,omitempty"`
+}
+
 type Identifier struct {
 	System string `json:"system"`
 	Value  string `json:"value"`
@@ -62,6 +74,7 @@ type Address struct {
 }
 
 type Reference struct {
-	Reference string `json:"reference"`
-	Display   string `json:"display,omitempty"`
+	Reference  string      `json:"reference,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+	Display    string      `json:"display,omitempty"`
 }
@@ -4,57 +4,83 @@ import "time"
 
 // Questionnaire represents a quality indicators questionnaire
 type Questionnaire struct {
-	ResourceType string              `json:"resourceType"`
-	ID           string              `json:"id"`
-	Name         string              `json:"name"`
-	Title        string              `json:"title"`
-	Status       string              `json:"status"`
-	Subject      Reference           `json:"subject,omitempty"`
-	Date         string              `json:"date"`
-	Publisher    string              `json:"publisher"`
-	Description  string              `json:"description"`
-	Item         []QuestionnaireItem `json:"item"`
+	ResourceType    string              `json:"resourceType"`
+	ID              string              `json:"id"`
+	URL             string              `json:"url,omitempty"`
+	Version         string              `json:"version,omitempty"`
+	Name            string              `json:"name"`
+	Title           string              `json:"title"`
+	Status          string              `json:"status"`
+	Subject         Reference           `json:"subject,omitempty"`
+	Date            string              `json:"date"`
+	Publisher       string              `json:"publisher"`
+	Description     string              `json:"description"`
+	EffectivePeriod *DatePeriod         `json:"effectivePeriod,omitempty"`
+	Item            []QuestionnaireItem `json:"item"`
 }
 
-// QuestionnaireItem represents an item in a questionnaire
+// DatePeriod is a period with YYYY-MM-DD dates. An empty End means the period is ongoing.
+type DatePeriod struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// QuestionnaireItem represents an item in a questionnaire.
+// EnableBehavior is "all" (the default) or "any" of the EnableWhen conditions.
 type QuestionnaireItem struct {
-	LinkID       string              `json:"linkId"`
-	Text         string              `json:"text"`
-	Type         string              `json:"type"`
-	Required     bool                `json:"required"`
-	Item         []QuestionnaireItem `json:"item,omitempty"`
-	AnswerOption []AnswerOption      `json:"answerOption,omitempty"`
-	EnableWhen   []EnableWhen        `json:"enableWhen,omitempty"`
+	LinkID         string              `json:"linkId"`
+	Text           string              `json:"text"`
+	Type           string              `json:"type"`
+	Required       bool                `json:"required"`
+	Item           []QuestionnaireItem `json:"item,omitempty"`
+	AnswerOption   []AnswerOption      `json:"answerOption,omitempty"`
+	EnableWhen     []EnableWhen        `json:"enableWhen,omitempty"`
+	EnableBehavior string              `json:"enableBehavior,omitempty"`
 }
 
-// AnswerOption represents possible answers for a questionnaire item
+// AnswerOption represents possible answers for a questionnaire item.
+// Exactly one value is set.
 type AnswerOption struct {
-	ValueInteger int    `json:"valueInteger,omitempty"`
-	ValueString  string `json:"valueString,omitempty"`
-	ValueBoolean bool   `json:"valueBoolean,omitempty"`
+	ValueInteger *int    `json:"valueInteger,omitempty"`
+	ValueString  *string `json:"valueString,omitempty"`
+	ValueBoolean *bool   `json:"valueBoolean,omitempty"`
+	ValueDate    *string `json:"valueDate,omitempty"`
 }
 
-// EnableWhen represents a condition for when a question should be enabled
+// EnableWhen represents a condition for when a question should be enabled.
+// Exactly one answer is set; the "exists" operator uses AnswerBoolean.
 type EnableWhen struct {
-	Question      string `json:"question"`
-	Operator      string `json:"operator"`
-	AnswerBoolean bool   `json:"answerBoolean,omitempty"`
-	AnswerInteger int    `json:"answerInteger,omitempty"`
-	AnswerString  string `json:"answerString,omitempty"`
+	Question      string  `json:"question"`
+	Operator      string  `json:"operator"`
+	AnswerBoolean *bool   `json:"answerBoolean,omitempty"`
+	AnswerInteger *int    `json:"answerInteger,omitempty"`
+	AnswerString  *string `json:"answerString,omitempty"`
+	AnswerDate    *string `json:"answerDate,omitempty"`
 }
 
 // QuestionnaireResponse represents a response to a quality indicators questionnaire
 type QuestionnaireResponse struct {
 	ResourceType  string                      `json:"resourceType"`
 	ID            string                      `json:"id"`
+	Meta          *Meta                       `json:"meta,omitempty"`
 	Questionnaire string                      `json:"questionnaire"`
 	Status        string                      `json:"status"`
 	Subject       Reference                   `json:"subject"`
 	AuthoredOn    time.Time                   `json:"authored"`
 	Author        Reference                   `json:"author"`
+	Extension     []Extension                 `json:"extension,omitempty"`
 	Item          []QuestionnaireResponseItem `json:"item"`
 }
 
+// Extension is a FHIR extension, used on QuestionnaireResponse for the
+// reporting period, due date, first submitted date and late submission flag
+type Extension struct {
+	URL          string      `json:"url"`
+	ValuePeriod  *DatePeriod `json:"valuePeriod,omitempty"`
+	ValueDate    *string     `json:"valueDate,omitempty"`
+	ValueBoolean *bool       `json:"valueBoolean,omitempty"`
+}
+
 // QuestionnaireResponseItem represents an item in a questionnaire response
 type QuestionnaireResponseItem struct {
 	LinkID string                      `json:"linkId"`
@@ -63,9 +89,12 @@ type QuestionnaireResponseItem struct {
 	Item   []QuestionnaireResponseItem `json:"item,omitempty"`
 }
 
-// QuestionnaireItemAnswer represents an answer to a questionnaire item
+// QuestionnaireItemAnswer represents an answer to a questionnaire item.
+// Values are pointers so that zero answers (0, "", false) are kept and the
+// answer type can be checked against the questionnaire.
 type QuestionnaireItemAnswer struct {
-	ValueInteger int    `json:"valueInteger,omitempty"`
-	ValueString  string `json:"valueString,omitempty"`
-	ValueBoolean bool   `json:"valueBoolean,omitempty"`
+	ValueInteger *int    `json:"valueInteger,omitempty"`
+	ValueString  *string `json:"valueString,omitempty"`
+	ValueBoolean *bool   `json:"valueBoolean,omitempty"`
+	ValueDate    *string `json:"valueDate,omitempty"`
 }
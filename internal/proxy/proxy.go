@@ -0,0 +1,327 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/redact"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+)
+
+// Modes selected by MOCK_MODE. The default, "mock", serves the mock handlers.
+const (
+	ModeMock   = "mock"
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// defaultDir holds the recordings when MOCK_RECORDINGS_DIR is not set
+const defaultDir = "recordings"
+
+// hopHeaders apply to a single connection and are not forwarded
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+	// Let the upstream reply uncompressed so the recordings are readable
+	"Accept-Encoding",
+}
+
+// unsafeChars are replaced in recording file names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FromEnv returns the proxy handler for MOCK_MODE, MOCK_UPSTREAM and
+// MOCK_RECORDINGS_DIR. ok is false in mock mode, or when the proxy cannot be
+// set up, in which case the mock handlers are used.
+func FromEnv(clk clock.Clock) (handler http.Handler, mode string, ok bool) {
+	mode = strings.ToLower(os.Getenv("MOCK_MODE"))
+	dir := os.Getenv("MOCK_RECORDINGS_DIR")
+	if dir == "" {
+		dir = defaultDir
+	}
+
+	switch mode {
+	case "", ModeMock:
+		return nil, ModeMock, false
+	case ModeRecord:
+		upstream := os.Getenv("MOCK_UPSTREAM")
+		rec, err := NewRecorder(upstream, dir, clk)
+		if err != nil {
+			log.Printf("Ignoring MOCK_MODE=record: %v", err)
+			return nil, ModeMock, false
+		}
+		log.Printf("Recording %s into %s", upstream, dir)
+		return rec, mode, true
+	case ModeReplay:
+		rep, err := NewReplayer(dir)
+		if err != nil {
+			log.Printf("Ignoring MOCK_MODE=replay: %v", err)
+			return nil, ModeMock, false
+		}
+		log.Printf("Replaying %d recording(s) from %s", rep.Len(), dir)
+		return rep, mode, true
+	default:
+		log.Printf("Ignoring unknown MOCK_MODE '%s', must be mock, record or replay", mode)
+		return nil, ModeMock, false
+	}
+}
+
+// Recorder forwards API calls to an upstream and saves each request and
+// response pair, redacted, as a JSON file
+type Recorder struct {
+	upstream *url.URL
+	dir      string
+	clk      clock.Clock
+	client   *http.Client
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewRecorder returns a recorder for an upstream base URL, e.g.
+// "https://api.example.gov.au/api", writing into dir
+func NewRecorder(upstream, dir string, clk clock.Clock) (*Recorder, error) {
+	u, err := url.Parse(upstream)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("MOCK_UPSTREAM must be an http or https URL, got '%s'", upstream)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	existing, err := recordingFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{
+		upstream: u,
+		dir:      dir,
+		clk:      clk,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+			// Pass redirects back to the caller as recorded responses
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		next: lastNumber(existing) + 1,
+	}, nil
+}
+
+// lastNumber returns the highest number prefixing the recording file names,
+// so new recordings sort after existing ones even when some were deleted
+func lastNumber(files []string) int {
+	last := 0
+	for _, file := range files {
+		digits := strings.SplitN(filepath.Base(file), "-", 2)[0]
+		if n, err := strconv.Atoi(digits); err == nil && n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+func (p *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := routematch.Path(r)
+	body, _ := io.ReadAll(r.Body)
+
+	target := *p.upstream
+	target.Path = strings.TrimSuffix(p.upstream.Path, "/") + route
+	target.RawQuery = r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "exception", err.Error()))
+		return
+	}
+	req.Header = r.Header.Clone()
+	for _, name := range hopHeaders {
+		req.Header.Del(name)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Printf("Proxy error for %s %s: %v", r.Method, route, err)
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "transient", "Upstream request failed: "+err.Error()))
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		render.Status(r, http.StatusBadGateway)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "transient", "Reading upstream response failed: "+err.Error()))
+		return
+	}
+	for _, name := range hopHeaders {
+		resp.Header.Del(name)
+	}
+
+	if err := p.save(route, r, body, resp, respBody); err != nil {
+		log.Printf("Could not save recording for %s %s: %v", r.Method, route, err)
+	}
+
+	writeResponse(w, resp.StatusCode, resp.Header, respBody)
+}
+
+// save writes a redacted recording, numbered so the files sort in call order
+func (p *Recorder) save(route string, r *http.Request, body []byte, resp *http.Response, respBody []byte) error {
+	rec := models.Recording{
+		RecordedAt: p.clk.Now(),
+		Route:      route,
+		Request: models.JournalRequest{
+			Method:  r.Method,
+			Path:    route,
+			Query:   redact.Query(r.URL.RawQuery),
+			Headers: redact.Headers(r.Header),
+			Body:    redact.Body(r.Header.Get("Content-Type"), string(body)),
+		},
+		Response: models.JournalResponse{
+			Status:  resp.StatusCode,
+			Headers: redact.Headers(resp.Header),
+			Body:    redact.Body(resp.Header.Get("Content-Type"), string(respBody)),
+		},
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	n := p.next
+	p.next++
+	p.mu.Unlock()
+
+	name := fmt.Sprintf("%04d-%s-%s.json", n, r.Method, strings.Trim(unsafeChars.ReplaceAllString(route, "_"), "_"))
+	return os.WriteFile(filepath.Join(p.dir, name), data, 0o644)
+}
+
+// Replayer serves recorded responses offline. Calls are matched on method,
+// route and query, preferring recordings with the same body; repeated calls
+// get the matching recordings in order, then the last one again.
+type Replayer struct {
+	mu         sync.Mutex
+	recordings map[string][]models.Recording
+	served     map[string]int
+}
+
+// NewReplayer loads the recordings in dir
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := recordingFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &Replayer{recordings: map[string][]models.Recording{}, served: map[string]int{}}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var rec models.Recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("invalid recording %s: %w", file, err)
+		}
+		key := replayKey(rec.Request.Method, rec.Route, rec.Request.Query)
+		p.recordings[key] = append(p.recordings[key], rec)
+	}
+	return p, nil
+}
+
+// Len returns the number of loaded recordings
+func (p *Replayer) Len() int {
+	n := 0
+	for _, recs := range p.recordings {
+		n += len(recs)
+	}
+	return n
+}
+
+func (p *Replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := routematch.Path(r)
+	body, _ := io.ReadAll(r.Body)
+
+	rec, ok := p.next(r.Method, route, redact.Query(r.URL.RawQuery),
+		redact.Body(r.Header.Get("Content-Type"), string(body)))
+	if !ok {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, models.NewOperationOutcome("ERROR", "not-found",
+			fmt.Sprintf("No recording for %s %s", r.Method, route)))
+		return
+	}
+	writeResponse(w, rec.Response.Status, rec.Response.Headers, []byte(rec.Response.Body))
+}
+
+// next picks the recording for a call, counting calls by key and body
+func (p *Replayer) next(method, route, query, body string) (models.Recording, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := replayKey(method, route, query)
+	candidates := p.recordings[key]
+	if len(candidates) == 0 {
+		return models.Recording{}, false
+	}
+	var sameBody []models.Recording
+	for _, rec := range candidates {
+		if rec.Request.Body == body {
+			sameBody = append(sameBody, rec)
+		}
+	}
+	if len(sameBody) > 0 {
+		candidates = sameBody
+		key += "\n" + body
+	}
+
+	n := p.served[key]
+	p.served[key]++
+	if n >= len(candidates) {
+		n = len(candidates) - 1
+	}
+	return candidates[n], true
+}
+
+func replayKey(method, route, query string) string {
+	return method + " " + route + "?" + query
+}
+
+// recordingFiles lists the JSON files in dir in name order
+func recordingFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// writeResponse writes a recorded or proxied response. Content-Length is left
+// to net/http, as redaction may have changed the body.
+func writeResponse(w http.ResponseWriter, status int, headers http.Header, body []byte) {
+	for name, values := range headers {
+		if strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		w.Header()[name] = append([]string(nil), values...)
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/redact"
+)
+
+// upstream is a fake API issuing a token and returning providers
+func upstream(t *testing.T) *httptest.Server {
+	t.Helper()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/oauth2/access-tokens":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), "client_secret=s3cret") {
+				w.WriteHeader(http.StatusUnauthorized)
+				io.WriteString(w, `{"error":"invalid_client"}`)
+				return
+			}
+			io.WriteString(w, `{"access_token":"upstream-token","token_type":"Bearer","expires_in":3600}`)
+		case "GET /api/Provider":
+			if r.Header.Get("Authorization") != "Bearer upstream-token" || r.Header.Get("Connection") != "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("X-Call", strings.Repeat("I", calls))
+			io.WriteString(w, `{"resourceType":"Bundle","total":`+r.URL.Query().Get("_count")+`}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func serve(handler http.Handler, method, target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+var (
+	tokenForm    = map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	bearerHeader = map[string]string{"Authorization": "Bearer upstream-token", "Connection": "keep-alive"}
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(upstream(t).URL+"/api", dir, clock.NewVirtual())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := serve(rec, "POST", "/oauth2/access-tokens", "grant_type=client_credentials&client_secret=s3cret", tokenForm)
+	if token.Code != http.StatusOK || !strings.Contains(token.Body.String(), "upstream-token") {
+		t.Fatalf("token: got %d %s, want the upstream token", token.Code, token.Body)
+	}
+	for _, count := range []string{"10", "10", "20"} {
+		if w := serve(rec, "GET", "/Provider?_count="+count, "", bearerHeader); w.Code != http.StatusOK {
+			t.Fatalf("Provider: got %d %s", w.Code, w.Body)
+		}
+	}
+
+	// The recordings are numbered in call order and have no secrets
+	files, _ := recordingFiles(dir)
+	if len(files) != 4 || filepath.Base(files[0]) != "0001-POST-oauth2_access-tokens.json" || filepath.Base(files[3]) != "0004-GET-Provider.json" {
+		t.Fatalf("got recordings %v", files)
+	}
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		if strings.Contains(string(data), "upstream-token") || strings.Contains(string(data), "s3cret") {
+			t.Errorf("%s has a secret: %s", file, data)
+		}
+	}
+
+	rep, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Len() != 4 {
+		t.Errorf("loaded %d recordings, want 4", rep.Len())
+	}
+
+	replayed := serve(rep, "POST", "/oauth2/access-tokens", "grant_type=client_credentials&client_secret=other", tokenForm)
+	var tokenResponse map[string]any
+	json.Unmarshal(replayed.Body.Bytes(), &tokenResponse)
+	if replayed.Code != http.StatusOK || tokenResponse["access_token"] != redact.Redacted || tokenResponse["token_type"] != "Bearer" {
+		t.Errorf("replayed token: got %d %s", replayed.Code, replayed.Body)
+	}
+
+	// Repeated calls get the recordings in order, then the last one again
+	tests := []struct{ target, body, call string }{
+		{"/Provider?_count=10", `{"resourceType":"Bundle","total":10}`, "II"},
+		{"/Provider?_count=10", `{"resourceType":"Bundle","total":10}`, "III"},
+		{"/Provider?_count=10", `{"resourceType":"Bundle","total":10}`, "III"},
+		{"/Provider?_count=20", `{"resourceType":"Bundle","total":20}`, "IIII"},
+	}
+	for _, tt := range tests {
+		w := serve(rep, "GET", tt.target, "", nil)
+		if w.Code != http.StatusOK || w.Body.String() != tt.body || w.Header().Get("X-Call") != tt.call {
+			t.Errorf("replayed %s: got %d %s (call %s), want %s (call %s)", tt.target, w.Code, w.Body, w.Header().Get("X-Call"), tt.body, tt.call)
+		}
+	}
+
+	missing := serve(rep, "GET", "/Provider?_count=30", "", nil)
+	var outcome models.OperationOutcome
+	json.Unmarshal(missing.Body.Bytes(), &outcome)
+	if missing.Code != http.StatusNotFound || len(outcome.Issue) == 0 || outcome.Issue[0].Severity != "ERROR" {
+		t.Errorf("unrecorded call: got %d %s, want a 404 OperationOutcome", missing.Code, missing.Body)
+	}
+}
+
+func TestRecorderNumbersAfterExisting(t *testing.T) {
+	dir := t.TempDir()
+	// 0002 was deleted, so numbering from the file count would reuse 0003
+	for _, name := range []string{"0001-GET-Provider.json", "0003-GET-Provider.json", "notes.txt"} {
+		os.WriteFile(filepath.Join(dir, name), []byte(`{}`), 0o644)
+	}
+
+	rec, err := NewRecorder(upstream(t).URL+"/api", dir, clock.NewVirtual())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(rec, "GET", "/Provider?_count=10", "", bearerHeader)
+
+	if _, err := os.Stat(filepath.Join(dir, "0004-GET-Provider.json")); err != nil {
+		t.Errorf("new recording is not 0004: %v", err)
+	}
+}
+
+func TestRecorderUpstreamDown(t *testing.T) {
+	server := upstream(t)
+	rec, err := NewRecorder(server.URL+"/api", t.TempDir(), clock.NewVirtual())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	w := serve(rec, "GET", "/Provider", "", nil)
+	var outcome models.OperationOutcome
+	json.Unmarshal(w.Body.Bytes(), &outcome)
+	if w.Code != http.StatusBadGateway || len(outcome.Issue) == 0 || outcome.Issue[0].Severity != "ERROR" || outcome.Issue[0].Code != "transient" {
+		t.Errorf("got %d %s, want a 502 transient OperationOutcome", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,123 @@
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces tokens and secrets in recordings and the request journal
+const Redacted = "REDACTED"
+
+// secretHeaders are redacted in requests and responses
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// secretFields are redacted in JSON bodies, form bodies and query strings
+var secretFields = map[string]bool{
+	"access_token":     true,
+	"refresh_token":    true,
+	"id_token":         true,
+	"client_secret":    true,
+	"client_assertion": true,
+	"jwt":              true,
+	"password":         true,
+	"x509":             true,
+}
+
+// Headers returns a copy of headers with secrets redacted. The
+// Authorization scheme is kept, e.g. "Bearer REDACTED".
+func Headers(headers http.Header) http.Header {
+	redacted := headers.Clone()
+	for _, name := range secretHeaders {
+		values := redacted.Values(name)
+		for i, value := range values {
+			if scheme, _, ok := strings.Cut(value, " "); ok && name == "Authorization" {
+				values[i] = scheme + " " + Redacted
+			} else {
+				values[i] = Redacted
+			}
+		}
+	}
+	return redacted
+}
+
+// Query returns a query string with secret parameters redacted
+func Query(rawQuery string) string {
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+	changed := false
+	for name, values := range query {
+		if secretFields[strings.ToLower(name)] {
+			for i := range values {
+				values[i] = Redacted
+			}
+			changed = true
+		}
+	}
+	if !changed {
+		return rawQuery
+	}
+	return query.Encode()
+}
+
+// Body redacts secret fields in a JSON or form encoded body. A body that is
+// not valid JSON but names a secret field, such as a cut off token response,
+// is replaced with Redacted.
+func Body(contentType, body string) string {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return Query(body)
+	}
+
+	var doc any
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		if namesSecret(body) {
+			return Redacted
+		}
+		return body
+	}
+	if !redactJSON(doc) {
+		return body
+	}
+	redacted, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return string(redacted)
+}
+
+// namesSecret reports whether a body has a quoted secret field name
+func namesSecret(body string) bool {
+	body = strings.ToLower(body)
+	for field := range secretFields {
+		if strings.Contains(body, `"`+field+`"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactJSON replaces secret fields at any depth, reporting whether any were found
+func redactJSON(value any) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if secretFields[strings.ToLower(key)] {
+				v[key] = Redacted
+				changed = true
+			} else if redactJSON(field) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if redactJSON(item) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,343 @@
+package scenarios
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+	"github.com/jasonchiu/dohac-mock-apis/internal/routematch"
+	"gopkg.in/yaml.v3"
+)
+
+// Header names the scenario to apply to a single request
+const Header = "X-Mock-Scenario"
+
+// Store of scenarios by name, and the scenario applied to requests without the header
+var (
+	mu        sync.Mutex
+	scenarios = map[string]*models.Scenario{}
+	active    string
+)
+
+// Parse reads one scenario or a list of scenarios from JSON or YAML
+func Parse(data []byte, isYAML bool) ([]models.Scenario, error) {
+	if isYAML {
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var list []models.Scenario
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("invalid scenarios: %w", err)
+		}
+		return list, nil
+	}
+	var scenario models.Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	return []models.Scenario{scenario}, nil
+}
+
+// IsYAML reports whether a file name or content type is for YAML
+func IsYAML(nameOrType string) bool {
+	nameOrType = strings.ToLower(nameOrType)
+	return strings.HasSuffix(nameOrType, ".yaml") || strings.HasSuffix(nameOrType, ".yml") || strings.Contains(nameOrType, "yaml")
+}
+
+// Validate checks that a scenario has a name and that every step matches a path
+// and has a response
+func Validate(s models.Scenario) error {
+	if s.Name == "" || s.Name == "active" {
+		return fmt.Errorf("scenario name is required and cannot be 'active'")
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario %s has no steps", s.Name)
+	}
+	for i, step := range s.Steps {
+		if !strings.HasPrefix(step.Match.Path, "/") {
+			return fmt.Errorf("scenario %s step %d: match.path must start with '/'", s.Name, i+1)
+		}
+		if len(step.Responses) == 0 {
+			return fmt.Errorf("scenario %s step %d: at least one response is required", s.Name, i+1)
+		}
+		for j, resp := range step.Responses {
+			if !resp.Passthrough && (resp.Status < 100 || resp.Status > 599) {
+				return fmt.Errorf("scenario %s step %d response %d: status must be a valid HTTP status, or set passthrough", s.Name, i+1, j+1)
+			}
+		}
+	}
+	return nil
+}
+
+// Put validates and stores scenarios, replacing those with the same names
+// and resetting their counters
+func Put(list []models.Scenario) error {
+	for _, s := range list {
+		if err := Validate(s); err != nil {
+			return err
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range list {
+		s := s
+		s.Steps = append([]models.ScenarioStep(nil), s.Steps...)
+		for i := range s.Steps {
+			s.Steps[i].Calls = 0
+			s.Steps[i].Match.Method = strings.ToUpper(s.Steps[i].Match.Method)
+		}
+		scenarios[s.Name] = &s
+	}
+	return nil
+}
+
+// List returns copies of the stored scenarios, sorted by name
+func List() []models.Scenario {
+	mu.Lock()
+	defer mu.Unlock()
+	list := []models.Scenario{}
+	for _, s := range scenarios {
+		list = append(list, snapshot(s))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get returns a copy of a stored scenario
+func Get(name string) (models.Scenario, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := scenarios[name]
+	if !ok {
+		return models.Scenario{}, false
+	}
+	return snapshot(s), true
+}
+
+// snapshot copies a scenario with its active flag. Callers must hold mu.
+func snapshot(s *models.Scenario) models.Scenario {
+	c := *s
+	c.Steps = append([]models.ScenarioStep(nil), s.Steps...)
+	c.Active = s.Name == active
+	return c
+}
+
+// Delete removes a scenario, deactivating it if it was active
+func Delete(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := scenarios[name]; !ok {
+		return false
+	}
+	delete(scenarios, name)
+	if active == name {
+		active = ""
+	}
+	return true
+}
+
+// Activate applies a scenario to requests without the header, with its
+// counters reset. An empty name deactivates the current scenario.
+func Activate(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if name == "" {
+		active = ""
+		return nil
+	}
+	s, ok := scenarios[name]
+	if !ok {
+		return fmt.Errorf("scenario %s not found", name)
+	}
+	resetCalls(s)
+	active = name
+	return nil
+}
+
+// Reset sets a scenario's counters back to zero
+func Reset(name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := scenarios[name]
+	if ok {
+		resetCalls(s)
+	}
+	return ok
+}
+
+func resetCalls(s *models.Scenario) {
+	for i := range s.Steps {
+		s.Steps[i].Calls = 0
+	}
+}
+
+// LoadFromEnv loads the scenarios in MOCK_SCENARIOS, a JSON or YAML file or a
+// directory of them, and activates MOCK_SCENARIO
+func LoadFromEnv() {
+	if path := os.Getenv("MOCK_SCENARIOS"); path != "" {
+		if err := LoadPath(path); err != nil {
+			log.Printf("Ignoring MOCK_SCENARIOS: %v", err)
+		}
+	}
+	if name := os.Getenv("MOCK_SCENARIO"); name != "" {
+		if err := Activate(name); err != nil {
+			log.Printf("Ignoring MOCK_SCENARIO: %v", err)
+		}
+	}
+}
+
+// LoadPath loads the scenarios in a file, or in the .json, .yaml and .yml files of a directory
+func LoadPath(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		files = nil
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && (strings.HasSuffix(name, ".json") || IsYAML(name)) {
+				files = append(files, filepath.Join(path, name))
+			}
+		}
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		list, err := Parse(data, IsYAML(file))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if err := Put(list); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		log.Printf("Loaded %d scenario(s) from %s", len(list), file)
+	}
+	return nil
+}
+
+// Respond finds the scripted response of a scenario for a request. ok is false
+// when no step matches or the step passes the request through. body is the
+// request body, which the caller has already read.
+func Respond(name string, r *http.Request, route string, body []byte) (resp models.ScenarioResponse, ok bool, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	s, found := scenarios[name]
+	if !found {
+		return resp, false, fmt.Errorf("scenario %s not found", name)
+	}
+
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if !matches(step.Match, r, route, body) {
+			continue
+		}
+		step.Calls++
+		n := step.Calls - 1
+		if n >= len(step.Responses) {
+			n = len(step.Responses) - 1
+		}
+		resp = step.Responses[n]
+		return resp, !resp.Passthrough, nil
+	}
+	return resp, false, nil
+}
+
+// Active returns the name of the scenario applied to requests without the header
+func Active() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return active
+}
+
+func matches(m models.RequestMatcher, r *http.Request, route string, body []byte) bool {
+	if m.Method != "" && m.Method != r.Method {
+		return false
+	}
+	if !routematch.Match(m.Path, route) {
+		return false
+	}
+	for name, value := range m.Headers {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+	query := r.URL.Query()
+	for name, value := range m.Query {
+		if query.Get(name) != value {
+			return false
+		}
+	}
+	if m.BodyContains != "" && !strings.Contains(string(body), m.BodyContains) {
+		return false
+	}
+	if len(m.Body) > 0 {
+		fields := bodyFields(r.Header.Get("Content-Type"), body)
+		for name, value := range m.Body {
+			got, ok := fields(name)
+			if !ok || got != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// bodyFields returns a lookup of the form fields, or the JSON fields by dotted
+// path, of a request body
+func bodyFields(contentType string, body []byte) func(name string) (string, bool) {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form, _ := url.ParseQuery(string(body))
+		return func(name string) (string, bool) {
+			_, ok := form[name]
+			return form.Get(name), ok
+		}
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return func(string) (string, bool) { return "", false }
+	}
+	return func(name string) (string, bool) {
+		value := doc
+		for _, key := range strings.Split(name, ".") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			if value, ok = object[key]; !ok {
+				return "", false
+			}
+		}
+		if s, ok := value.(string); ok {
+			return s, true
+		}
+		return fmt.Sprint(value), true
+	}
+}
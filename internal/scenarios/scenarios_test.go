@@ -0,0 +1,195 @@
+package scenarios
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// tokenOutage fails the first two token requests, then lets them through
+const tokenOutage = `
+name: token-outage
+steps:
+  - match:
+      method: post
+      path: /oauth2/access-tokens
+      body:
+        grant_type: client_credentials
+    responses:
+      - status: 503
+      - status: 429
+        headers:
+          Retry-After: "1"
+      - passthrough: true
+  - match:
+      path: /Provider/{id}
+    responses:
+      - status: 404
+        body: {"resourceType": "OperationOutcome"}
+`
+
+// removeAll removes every scenario
+func removeAll() {
+	mu.Lock()
+	defer mu.Unlock()
+	scenarios = map[string]*models.Scenario{}
+	active = ""
+}
+
+func load(t *testing.T) {
+	t.Helper()
+	removeAll()
+	list, err := Parse([]byte(tokenOutage), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Put(list); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// respond sends a request through the named scenario, returning the scripted
+// status, or 0 when the request reaches the API
+func respond(t *testing.T, name, method, route, body string) int {
+	t.Helper()
+	r := httptest.NewRequest(method, "/api"+route, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, ok, err := Respond(name, r, route, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		return 0
+	}
+	return resp.Status
+}
+
+func TestRespondAdvancesAndRepeatsLast(t *testing.T) {
+	load(t)
+	defer removeAll()
+
+	token := func() int {
+		return respond(t, "token-outage", "POST", "/oauth2/access-tokens", "grant_type=client_credentials&client_id=test")
+	}
+	for i, want := range []int{503, 429, 0, 0, 0} {
+		if got := token(); got != want {
+			t.Errorf("token request %d: got %d, want %d", i+1, got, want)
+		}
+	}
+
+	// The second step counts its own calls and repeats its only response
+	for i := 0; i < 3; i++ {
+		if got := respond(t, "token-outage", "GET", "/Provider/PRV-1", ""); got != 404 {
+			t.Errorf("provider request %d: got %d, want 404", i+1, got)
+		}
+	}
+
+	s, _ := Get("token-outage")
+	if s.Steps[0].Calls != 5 || s.Steps[1].Calls != 3 {
+		t.Errorf("got %d and %d calls, want 5 and 3", s.Steps[0].Calls, s.Steps[1].Calls)
+	}
+}
+
+func TestRespondNoMatch(t *testing.T) {
+	load(t)
+	defer removeAll()
+
+	tests := []struct{ method, route, body string }{
+		{"GET", "/oauth2/access-tokens", "grant_type=client_credentials"},
+		{"POST", "/oauth2/access-tokens", "grant_type=refresh_token"},
+		{"GET", "/Provider", ""},
+	}
+	for _, tt := range tests {
+		if got := respond(t, "token-outage", tt.method, tt.route, tt.body); got != 0 {
+			t.Errorf("%s %s %q: got scripted %d, want no match", tt.method, tt.route, tt.body, got)
+		}
+	}
+	if s, _ := Get("token-outage"); s.Steps[0].Calls != 0 {
+		t.Errorf("unmatched requests counted %d calls", s.Steps[0].Calls)
+	}
+
+	r := httptest.NewRequest("GET", "/api/Provider", nil)
+	if _, _, err := Respond("missing", r, "/Provider", nil); err == nil {
+		t.Error("no error for an unknown scenario")
+	}
+}
+
+func TestReset(t *testing.T) {
+	load(t)
+	defer removeAll()
+
+	token := func() int {
+		return respond(t, "token-outage", "POST", "/oauth2/access-tokens", "grant_type=client_credentials")
+	}
+	token()
+	token()
+
+	if !Reset("token-outage") {
+		t.Fatal("token-outage not found")
+	}
+	if got := token(); got != 503 {
+		t.Errorf("after Reset: got %d, want 503", got)
+	}
+
+	token()
+	if err := Activate("token-outage"); err != nil {
+		t.Fatal(err)
+	}
+	if got := token(); got != 503 {
+		t.Errorf("after Activate: got %d, want 503", got)
+	}
+
+	if Reset("missing") {
+		t.Error("reset an unknown scenario")
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	removeAll()
+	defer removeAll()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token-outage.yaml"), []byte(tokenOutage), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "provider-down.json"), []byte(`[{"name": "provider-down", "steps": [{"match": {"path": "/Provider/*"}, "responses": [{"status": 503}]}]}]`), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a scenario"), 0o644)
+
+	t.Setenv("MOCK_SCENARIOS", dir)
+	t.Setenv("MOCK_SCENARIO", "token-outage")
+	LoadFromEnv()
+
+	list := List()
+	if len(list) != 2 || list[0].Name != "provider-down" || list[1].Name != "token-outage" {
+		t.Fatalf("got scenarios %v, want provider-down and token-outage", list)
+	}
+	if Active() != "token-outage" || !list[1].Active || list[0].Active {
+		t.Errorf("active scenario is %q, want token-outage", Active())
+	}
+	if s := list[1]; s.Steps[0].Match.Method != "POST" {
+		t.Errorf("got method %q, want it upper-cased", s.Steps[0].Match.Method)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, data := range []string{
+		`{"name": "", "steps": [{"match": {"path": "/Provider"}, "responses": [{"status": 503}]}]}`,
+		`{"name": "active", "steps": [{"match": {"path": "/Provider"}, "responses": [{"status": 503}]}]}`,
+		`{"name": "none", "steps": []}`,
+		`{"name": "relative", "steps": [{"match": {"path": "Provider"}, "responses": [{"status": 503}]}]}`,
+		`{"name": "silent", "steps": [{"match": {"path": "/Provider"}, "responses": []}]}`,
+		`{"name": "no-status", "steps": [{"match": {"path": "/Provider"}, "responses": [{}]}]}`,
+	} {
+		list, err := Parse([]byte(data), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Put(list); err == nil {
+			t.Errorf("%s: no error", data)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// Search entry modes
+const (
+	ModeMatch   = "match"
+	ModeInclude = "include"
+)
+
+// NewBundle creates an empty searchset bundle with navigation links for the requested page
+func NewBundle(r *http.Request, id string, total int, p Paging) models.Bundle {
+	return models.Bundle{
+		ResourceType: "Bundle",
+		ID:           id,
+		Type:         "searchset",
+		Total:        total,
+		Link:         Links(r, total, p),
+		Entry:        []models.BundleEntry{},
+	}
+}
+
+// AddMatch appends a resource that matched the search to the bundle
+func AddMatch(bundle *models.Bundle, fullURL string, resource any) {
+	bundle.Entry = append(bundle.Entry, models.BundleEntry{
+		FullURL:  fullURL,
+		Resource: resource,
+		Search:   &models.BundleEntrySearch{Mode: ModeMatch},
+	})
+}
+
+// FullURL returns the absolute URL of a resource found by a search on the
+// request's endpoint, e.g. https://host/api/Provider/PRV-12345
+func FullURL(r *http.Request, id string) string {
+	return BaseURL(r) + strings.TrimSuffix(r.URL.Path, "/") + "/" + id
+}
@@ -0,0 +1,343 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParamType is the FHIR search parameter type, which decides how values are matched
+type ParamType int
+
+const (
+	// StringParam matches case-insensitively from the start of the value, or
+	// with the :contains and :exact modifiers
+	StringParam ParamType = iota
+	// TokenParam matches system|code pairs, e.g. identifier, type and active
+	TokenParam
+	// DateParam matches dates with an optional eq/ne/gt/lt/ge/le prefix
+	DateParam
+)
+
+// Token is a coded value with an optional system
+type Token struct {
+	System string
+	Code   string
+}
+
+// Param describes a search parameter for resources of type T. Only the
+// extractor matching the parameter's Type needs to be set.
+type Param[T any] struct {
+	Type    ParamType
+	Strings func(T) []string
+	Tokens  func(T) []Token
+	Dates   func(T) []time.Time
+}
+
+// Engine searches resources of type T using its registered parameters.
+// Query parameters without a registered definition are left to the caller.
+type Engine[T any] struct {
+	Params map[string]Param[T]
+}
+
+// resultParams are search result parameters rather than filters
+var resultParams = map[string]bool{
+	"_count":      true,
+	"page":        true,
+	"_sort":       true,
+	"_elements":   true,
+	"_include":    true,
+	"_revinclude": true,
+	"_format":     true,
+}
+
+// Search filters items by every registered parameter in the query and then
+// applies _sort. Comma-separated values are ORed; repeated parameters are ANDed.
+func (e Engine[T]) Search(query url.Values, items []T) ([]T, error) {
+	type filter struct {
+		name, modifier string
+		param          Param[T]
+		values         []string
+	}
+
+	var filters []filter
+	for key, values := range query {
+		if resultParams[key] {
+			continue
+		}
+		name, modifier, _ := strings.Cut(key, ":")
+		param, ok := e.Params[name]
+		if !ok {
+			continue
+		}
+		if err := checkModifier(name, modifier, param.Type); err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			f := filter{name: name, modifier: modifier, param: param, values: strings.Split(value, ",")}
+			if param.Type == DateParam {
+				for _, v := range f.values {
+					if _, _, _, err := parseDateValue(v); err != nil {
+						return nil, fmt.Errorf("invalid %s '%s': %v", name, v, err)
+					}
+				}
+			}
+			filters = append(filters, f)
+		}
+	}
+
+	results := []T{}
+	for _, item := range items {
+		matched := true
+		for _, f := range filters {
+			if !matchAny(f.param, f.modifier, f.values, item) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, item)
+		}
+	}
+
+	if sortBy := query.Get("_sort"); sortBy != "" {
+		if err := e.sort(sortBy, results); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// sort orders items by a comma-separated list of parameters, each optionally prefixed with "-" for descending
+func (e Engine[T]) sort(sortBy string, items []T) error {
+	type key struct {
+		param Param[T]
+		desc  bool
+	}
+
+	var keys []key
+	for _, name := range strings.Split(sortBy, ",") {
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		param, ok := e.Params[name]
+		if !ok {
+			return fmt.Errorf("unsupported _sort parameter '%s'", name)
+		}
+		keys = append(keys, key{param: param, desc: desc})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, k := range keys {
+			c := compareFirst(k.param, items[i], items[j])
+			if c == 0 {
+				continue
+			}
+			if k.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return nil
+}
+
+// checkModifier rejects modifiers the parameter type does not support
+func checkModifier(name, modifier string, t ParamType) error {
+	switch {
+	case modifier == "":
+		return nil
+	case t == StringParam && (modifier == "contains" || modifier == "exact"):
+		return nil
+	case t == TokenParam && modifier == "not":
+		return nil
+	}
+	return fmt.Errorf("unsupported modifier '%s:%s'", name, modifier)
+}
+
+// matchAny reports whether the item matches any of the values
+func matchAny[T any](param Param[T], modifier string, values []string, item T) bool {
+	if param.Type == TokenParam && modifier == "not" {
+		for _, v := range values {
+			if matchToken(param.Tokens(item), v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, v := range values {
+		switch param.Type {
+		case StringParam:
+			if matchString(param.Strings(item), modifier, v) {
+				return true
+			}
+		case TokenParam:
+			if matchToken(param.Tokens(item), v) {
+				return true
+			}
+		case DateParam:
+			if matchDate(param.Dates(item), v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchString(candidates []string, modifier, value string) bool {
+	for _, c := range candidates {
+		switch modifier {
+		case "exact":
+			if c == value {
+				return true
+			}
+		case "contains":
+			if strings.Contains(strings.ToLower(c), strings.ToLower(value)) {
+				return true
+			}
+		default:
+			if strings.HasPrefix(strings.ToLower(c), strings.ToLower(value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchToken matches "code", "system|code", "|code" (no system) and "system|" (any code)
+func matchToken(candidates []Token, value string) bool {
+	system, code, hasSystem := strings.Cut(value, "|")
+	if !hasSystem {
+		code, system = system, ""
+	}
+
+	for _, c := range candidates {
+		if hasSystem && c.System != system {
+			continue
+		}
+		if code == "" || c.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func matchDate(candidates []time.Time, value string) bool {
+	prefix, start, end, err := parseDateValue(value)
+	if err != nil {
+		return false
+	}
+
+	for _, c := range candidates {
+		var ok bool
+		switch prefix {
+		case "eq":
+			ok = !c.Before(start) && c.Before(end)
+		case "ne":
+			ok = c.Before(start) || !c.Before(end)
+		case "gt":
+			ok = !c.Before(end)
+		case "lt":
+			ok = c.Before(start)
+		case "ge":
+			ok = !c.Before(start)
+		case "le":
+			ok = c.Before(end)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dateLayouts are the accepted date precisions, from most to least precise
+var dateLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{time.RFC3339, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02T15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// parseDateValue splits a date search value into its prefix and the
+// [start, end) range covered by the value's precision
+func parseDateValue(value string) (prefix string, start, end time.Time, err error) {
+	prefix = "eq"
+	if len(value) > 2 {
+		switch value[:2] {
+		case "eq", "ne", "gt", "lt", "ge", "le":
+			prefix, value = value[:2], value[2:]
+		}
+	}
+
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l.layout, value); err == nil {
+			return prefix, t, l.next(t), nil
+		}
+	}
+	return prefix, start, end, fmt.Errorf("expected a date such as 2024, 2024-07, 2024-07-01 or 2024-07-01T09:00:00Z")
+}
+
+// compareFirst compares two items on the first value of a parameter
+func compareFirst[T any](param Param[T], a, b T) int {
+	switch param.Type {
+	case StringParam:
+		return strings.Compare(strings.ToLower(first(param.Strings(a))), strings.ToLower(first(param.Strings(b))))
+	case TokenParam:
+		return strings.Compare(first(tokenCodes(param.Tokens(a))), first(tokenCodes(param.Tokens(b))))
+	case DateParam:
+		return first(param.Dates(a)).Compare(first(param.Dates(b)))
+	}
+	return 0
+}
+
+func first[V any](values []V) V {
+	var zero V
+	if len(values) == 0 {
+		return zero
+	}
+	return values[0]
+}
+
+func tokenCodes(tokens []Token) []string {
+	codes := make([]string, len(tokens))
+	for i, t := range tokens {
+		codes[i] = t.Code
+	}
+	return codes
+}
+
+// Elements returns a summary of a resource holding only the top-level elements
+// listed in the comma-separated _elements value, plus resourceType and id.
+// The resource is returned unchanged when elements is empty.
+func Elements(resource any, elements string) (any, error) {
+	if elements == "" {
+		return resource, nil
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]any
+	if err := json.Unmarshal(data, &full); err != nil {
+		return nil, err
+	}
+
+	subset := map[string]any{}
+	for _, key := range append([]string{"resourceType", "id"}, strings.Split(elements, ",")...) {
+		if value, ok := full[strings.TrimSpace(key)]; ok {
+			subset[strings.TrimSpace(key)] = value
+		}
+	}
+	return subset, nil
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testResource struct {
+	ID      string
+	Name    string
+	Type    []Token
+	Created time.Time
+	Rank    int
+}
+
+var testEngine = Engine[testResource]{Params: map[string]Param[testResource]{
+	"name":    {Type: StringParam, Strings: func(r testResource) []string { return []string{r.Name} }},
+	"type":    {Type: TokenParam, Tokens: func(r testResource) []Token { return r.Type }},
+	"created": {Type: DateParam, Dates: func(r testResource) []time.Time { return []time.Time{r.Created} }},
+}}
+
+var testResources = []testResource{
+	{ID: "a", Name: "Sunset Aged Care", Type: []Token{{System: "urn:ptype", Code: "RACF"}}, Created: time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)},
+	{ID: "b", Name: "Sunrise Care", Type: []Token{{System: "urn:ptype", Code: "HOME"}}, Created: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+	{ID: "c", Name: "Harbour sunset", Type: []Token{{System: "urn:other", Code: "RACF"}}, Created: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)},
+	{ID: "d", Name: "sunset", Type: []Token{{Code: "RACF"}}, Created: time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC)},
+}
+
+func searchIDs(t *testing.T, rawQuery string) []string {
+	t.Helper()
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := testEngine.Search(query, append([]testResource(nil), testResources...))
+	if err != nil {
+		t.Fatalf("Search(%q): %v", rawQuery, err)
+	}
+	ids := []string{}
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearchFilters(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// Strings match case-insensitively from the start
+		{"name=sun", []string{"a", "b", "d"}},
+		{"name:contains=SUNSET", []string{"a", "c", "d"}},
+		{"name:exact=sunset", []string{"d"}},
+		{"name:exact=Sunset", []string{}},
+		// Comma-separated values are ORed, repeated parameters ANDed
+		{"name=harbour,sunrise", []string{"b", "c"}},
+		{"name=sun&name:contains=care", []string{"a", "b"}},
+
+		// Tokens
+		{"type=RACF", []string{"a", "c", "d"}},
+		{"type=urn:ptype|RACF", []string{"a"}},
+		{"type=|RACF", []string{"d"}},
+		{"type=urn:ptype|", []string{"a", "b"}},
+		{"type:not=RACF", []string{"b"}},
+		{"type:not=urn:ptype|RACF,HOME", []string{"c", "d"}},
+
+		// Dates cover the range of their precision
+		{"created=2024-07-01", []string{"b", "c"}},
+		{"created=eq2024-07", []string{"b", "c", "d"}},
+		{"created=ne2024-07-01", []string{"a", "d"}},
+		{"created=gt2024-07-01", []string{"d"}},
+		{"created=ge2024-07-01", []string{"b", "c", "d"}},
+		{"created=lt2024-07-01", []string{"a"}},
+		{"created=le2024-07-01", []string{"a", "b", "c"}},
+		{"created=gt2024-07-01T00:00:00Z", []string{"c", "d"}},
+		{"created=lt2024-07-01T00:00:00Z", []string{"a"}},
+		{"created=2024", []string{"a", "b", "c", "d"}},
+
+		// Unknown and result parameters are left to the caller
+		{"unknown=x&_count=1&page=9", []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		if got := searchIDs(t, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchSort(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"_sort=name", []string{"c", "b", "d", "a"}},
+		{"_sort=-created", []string{"d", "c", "b", "a"}},
+		// Ties on the first key are ordered by the second
+		{"_sort=type,-name", []string{"b", "a", "d", "c"}},
+		{"_sort=-type,created", []string{"a", "c", "d", "b"}},
+	}
+	for _, tt := range tests {
+		if got := searchIDs(t, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	for _, rawQuery := range []string{
+		"name:not=x",
+		"type:contains=x",
+		"created:exact=2024",
+		"created=2024-13",
+		"created=gtyesterday",
+		"_sort=rank",
+	} {
+		query, _ := url.ParseQuery(rawQuery)
+		if _, err := testEngine.Search(query, testResources); err == nil {
+			t.Errorf("Search(%q) was accepted", rawQuery)
+		}
+	}
+}
+
+func TestElements(t *testing.T) {
+	resource := map[string]any{"resourceType": "Organization", "id": "PRV-1", "name": "Sunset", "active": true, "address": []string{"x"}}
+
+	got, err := Elements(resource, "name, active,missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"resourceType": "Organization", "id": "PRV-1", "name": "Sunset", "active": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Elements = %v, want %v", got, want)
+	}
+
+	if got, _ := Elements(resource, ""); !reflect.DeepEqual(got, resource) {
+		t.Errorf("Elements without _elements = %v, want the resource unchanged", got)
+	}
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// Linked is a resource found by following a reference for _include or _revinclude
+type Linked struct {
+	// Endpoint is the API path the resource is served from, e.g. "Provider"
+	Endpoint string
+	ID       string
+	Resource any
+}
+
+// Resolver returns the resources linked to a matched resource. Resolvers are
+// given the request so they can apply the caller's token restrictions.
+type Resolver func(r *http.Request, resource any) []Linked
+
+// Resolvers are keyed by the searched endpoint and the parameter value, e.g.
+// "Provider|HealthcareService:organization"
+var (
+	includeMu   sync.RWMutex
+	includes    = map[string]Resolver{}
+	revIncludes = map[string]Resolver{}
+)
+
+// RegisterInclude registers the resolver for an _include value such as
+// "HealthcareService:providedBy" on searches of an endpoint. The resolver
+// follows a reference on the matched resource.
+func RegisterInclude(endpoint, name string, resolve Resolver) {
+	includeMu.Lock()
+	defer includeMu.Unlock()
+	includes[endpoint+"|"+name] = resolve
+}
+
+// RegisterRevInclude registers the resolver for a _revinclude value such as
+// "HealthcareService:organization" on searches of an endpoint. The resolver
+// finds resources referencing the matched resource.
+func RegisterRevInclude(endpoint, name string, resolve Resolver) {
+	includeMu.Lock()
+	defer includeMu.Unlock()
+	revIncludes[endpoint+"|"+name] = resolve
+}
+
+// AddIncludes resolves the request's _include and _revinclude parameters for
+// the resources matched by a search of endpoint and appends the results to the
+// bundle with search mode "include". Resources already in the bundle are not repeated.
+func AddIncludes(r *http.Request, endpoint string, bundle *models.Bundle, matches []any) error {
+	includeMu.RLock()
+	defer includeMu.RUnlock()
+
+	var resolvers []Resolver
+	for _, kind := range []struct {
+		param     string
+		resolvers map[string]Resolver
+	}{
+		{"_include", includes},
+		{"_revinclude", revIncludes},
+	} {
+		for _, value := range r.URL.Query()[kind.param] {
+			for _, name := range strings.Split(value, ",") {
+				resolve, ok := kind.resolvers[endpoint+"|"+name]
+				if !ok {
+					return fmt.Errorf("unsupported %s '%s' on %s", kind.param, name, endpoint)
+				}
+				resolvers = append(resolvers, resolve)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range bundle.Entry {
+		seen[entry.FullURL] = true
+	}
+
+	for _, resolve := range resolvers {
+		for _, match := range matches {
+			for _, linked := range resolve(r, match) {
+				fullURL := ResourceURL(r, linked.Endpoint, linked.ID)
+				if seen[fullURL] {
+					continue
+				}
+				seen[fullURL] = true
+				bundle.Entry = append(bundle.Entry, models.BundleEntry{
+					FullURL:  fullURL,
+					Resource: linked.Resource,
+					Search:   &models.BundleEntrySearch{Mode: ModeInclude},
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// ResourceURL returns the absolute URL of a resource on another endpoint of
+// the same API, e.g. https://host/api/Provider/PRV-12345 from a HealthcareService search
+func ResourceURL(r *http.Request, endpoint, id string) string {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[:i]
+	}
+	return BaseURL(r) + path + "/" + endpoint + "/" + id
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+func init() {
+	// Test resources: a "Widget" references its "Maker", reachable through two include names
+	maker := func(r *http.Request, resource any) []Linked {
+		return []Linked{{Endpoint: "Maker", ID: "M-1", Resource: "maker 1"}}
+	}
+	RegisterInclude("Widget", "Widget:maker", maker)
+	RegisterInclude("Widget", "Widget:organization", maker)
+	RegisterRevInclude("Maker", "Widget:maker", func(r *http.Request, resource any) []Linked {
+		return []Linked{
+			{Endpoint: "Widget", ID: "W-1", Resource: "widget 1"},
+			{Endpoint: "Widget", ID: "W-2", Resource: "widget 2"},
+		}
+	})
+}
+
+func includeBundle(t *testing.T, target, endpoint string, matched ...string) (models.Bundle, error) {
+	t.Helper()
+	r := httptest.NewRequest("GET", target, nil)
+	bundle := NewBundle(r, "test", len(matched), Paging{Count: DefaultCount, Page: 1})
+	var page []any
+	for _, id := range matched {
+		AddMatch(&bundle, FullURL(r, id), id)
+		page = append(page, id)
+	}
+	err := AddIncludes(r, endpoint, &bundle, page)
+	return bundle, err
+}
+
+func TestAddIncludesUnknown(t *testing.T) {
+	tests := []struct{ endpoint, query string }{
+		{"Widget", "_include=Widget:colour"},
+		{"Widget", "_include=Widget:maker,Widget:colour"},
+		// Names are registered per searched endpoint and direction
+		{"Widget", "_revinclude=Widget:maker"},
+		{"Maker", "_include=Widget:maker"},
+	}
+	for _, tt := range tests {
+		target := "http://example.com/api/" + tt.endpoint + "?" + tt.query
+		if _, err := includeBundle(t, target, tt.endpoint, "X-1"); err == nil {
+			t.Errorf("%s was accepted", target)
+		}
+	}
+}
+
+func TestAddIncludesDeduplicates(t *testing.T) {
+	// Both matches reference the same maker, which both include names reach
+	bundle, err := includeBundle(t, "http://example.com/api/Widget?_include=Widget:maker&_include=Widget:organization", "Widget", "W-1", "W-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ url, mode string }{
+		{"http://example.com/api/Widget/W-1", ModeMatch},
+		{"http://example.com/api/Widget/W-2", ModeMatch},
+		{"http://example.com/api/Maker/M-1", ModeInclude},
+	}
+	if len(bundle.Entry) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(bundle.Entry), len(want), bundle.Entry)
+	}
+	for i, w := range want {
+		if e := bundle.Entry[i]; e.FullURL != w.url || e.Search.Mode != w.mode {
+			t.Errorf("entry %d is %s (%s), want %s (%s)", i, e.FullURL, e.Search.Mode, w.url, w.mode)
+		}
+	}
+}
+
+func TestAddIncludesSkipsMatches(t *testing.T) {
+	// A reverse include reaching a resource that already matched does not repeat it
+	bundle, err := includeBundle(t, "http://example.com/api/Maker?_revinclude=Widget:maker", "Maker", "M-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Entry) != 3 {
+		t.Fatalf("got %d entries, want the maker and two widgets", len(bundle.Entry))
+	}
+
+	r := httptest.NewRequest("GET", "http://example.com/api/Widget?_include=Widget:maker", nil)
+	bundle = NewBundle(r, "test", 1, Paging{Count: DefaultCount, Page: 1})
+	AddMatch(&bundle, ResourceURL(r, "Maker", "M-1"), "maker 1")
+	if err := AddIncludes(r, "Widget", &bundle, []any{"W-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Entry) != 1 {
+		t.Errorf("included resource already in the bundle was repeated: %+v", bundle.Entry)
+	}
+}
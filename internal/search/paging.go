@@ -0,0 +1,132 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// Paging limits from the Registered Nurses OAS (_count minimum 1, maximum 80)
+const (
+	DefaultCount = 10
+	MaxCount     = 80
+)
+
+// MaxPage keeps the offset of any page, (page - 1) * _count, within an int
+const MaxPage = math.MaxInt / MaxCount
+
+// Paging holds the parsed _count and page query parameters
+type Paging struct {
+	Count int
+	Page  int
+}
+
+// ParsePaging reads _count and page from the request, applying the defaults
+// when they are absent and rejecting values outside the spec's limits
+func ParsePaging(r *http.Request) (Paging, error) {
+	p := Paging{Count: DefaultCount, Page: 1}
+
+	if countStr := r.URL.Query().Get("_count"); countStr != "" {
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count < 1 || count > MaxCount {
+			return p, fmt.Errorf("invalid _count '%s', must be an integer between 1 and %d", countStr, MaxCount)
+		}
+		p.Count = count
+	}
+
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil || page < 1 || page > MaxPage {
+			return p, fmt.Errorf("invalid page '%s', must be an integer between 1 and %d", pageStr, MaxPage)
+		}
+		p.Page = page
+	}
+
+	return p, nil
+}
+
+// LastPage returns the number of the last page for a result set, which is 1 for an empty set
+func (p Paging) LastPage(total int) int {
+	if total == 0 {
+		return 1
+	}
+	return (total + p.Count - 1) / p.Count
+}
+
+// Paginate returns the items on the requested page. Pages past the end are empty.
+func Paginate[T any](items []T, p Paging) []T {
+	// Compare page numbers before multiplying, so that a huge page cannot overflow
+	if p.Page < 1 || p.Count < 1 || p.Page > p.LastPage(len(items)) || len(items) == 0 {
+		return []T{}
+	}
+	start := (p.Page - 1) * p.Count
+	end := start + p.Count
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// Links builds the self, first, previous, next and last links for a page of
+// results. URLs use the incoming request's scheme, host, path and query so
+// that every other filter is carried through.
+func Links(r *http.Request, total int, p Paging) []models.BundleLink {
+	last := p.LastPage(total)
+
+	links := []models.BundleLink{
+		{Relation: "self", URL: pageURL(r, p.Count, p.Page)},
+		{Relation: "first", URL: pageURL(r, p.Count, 1)},
+	}
+	if p.Page > 1 {
+		prev := p.Page - 1
+		if prev > last {
+			prev = last
+		}
+		links = append(links, models.BundleLink{Relation: "previous", URL: pageURL(r, p.Count, prev)})
+	}
+	if p.Page < last {
+		links = append(links, models.BundleLink{Relation: "next", URL: pageURL(r, p.Count, p.Page+1)})
+	}
+	links = append(links, models.BundleLink{Relation: "last", URL: pageURL(r, p.Count, last)})
+
+	return links
+}
+
+// SetLinkHeader adds the navigation links as an RFC 8288 Link header
+func SetLinkHeader(w http.ResponseWriter, links []models.BundleLink) {
+	parts := make([]string, 0, len(links))
+	for _, link := range links {
+		parts = append(parts, fmt.Sprintf("<%s>; rel=\"%s\"", link.URL, link.Relation))
+	}
+	w.Header().Set("Link", strings.Join(parts, ", "))
+}
+
+// BaseURL returns the scheme and host the request was made to, honouring
+// X-Forwarded-Proto when running behind a proxy such as Fly.io
+func BaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// pageURL rebuilds the request URL with the given _count and page
+func pageURL(r *http.Request, count, page int) string {
+	query := url.Values{}
+	for key, values := range r.URL.Query() {
+		query[key] = values
+	}
+	query.Set("_count", strconv.Itoa(count))
+	query.Set("page", strconv.Itoa(page))
+
+	return BaseURL(r) + r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,90 @@
+package search
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParsePaging(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Paging
+		ok    bool
+	}{
+		{"", Paging{Count: DefaultCount, Page: 1}, true},
+		{"_count=1&page=3", Paging{Count: 1, Page: 3}, true},
+		{"_count=80", Paging{Count: 80, Page: 1}, true},
+		{"page=" + strconv.Itoa(MaxPage), Paging{Count: DefaultCount, Page: MaxPage}, true},
+		{"_count=0", Paging{}, false},
+		{"_count=81", Paging{}, false},
+		{"_count=ten", Paging{}, false},
+		{"page=0", Paging{}, false},
+		{"page=-1", Paging{}, false},
+		{"page=" + strconv.Itoa(MaxPage+1), Paging{}, false},
+		{"_count=80&page=230584300921369396", Paging{}, false},
+		{"page=99999999999999999999", Paging{}, false},
+	}
+	for _, tt := range tests {
+		p, err := ParsePaging(httptest.NewRequest("GET", "/Provider?"+tt.query, nil))
+		if (err == nil) != tt.ok {
+			t.Errorf("ParsePaging(%q) error %v, want ok %v", tt.query, err, tt.ok)
+			continue
+		}
+		if tt.ok && p != tt.want {
+			t.Errorf("ParsePaging(%q) = %+v, want %+v", tt.query, p, tt.want)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	tests := []struct {
+		name  string
+		items []int
+		p     Paging
+		want  []int
+	}{
+		{"first page", items, Paging{Count: 2, Page: 1}, []int{1, 2}},
+		{"partial last page", items, Paging{Count: 2, Page: 3}, []int{5}},
+		{"past the end", items, Paging{Count: 2, Page: 4}, []int{}},
+		{"empty set", nil, Paging{Count: 10, Page: 1}, []int{}},
+		{"page that would overflow", items, Paging{Count: 80, Page: 230584300921369396}, []int{}},
+		{"largest page", items, Paging{Count: MaxCount, Page: MaxPage}, []int{}},
+	}
+	for _, tt := range tests {
+		if got := Paginate(tt.items, tt.p); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Paginate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		total int
+		p     Paging
+		want  map[string]int
+	}{
+		{"middle page", 25, Paging{Count: 10, Page: 2}, map[string]int{"self": 2, "first": 1, "previous": 1, "next": 3, "last": 3}},
+		{"first page", 25, Paging{Count: 10, Page: 1}, map[string]int{"self": 1, "first": 1, "next": 2, "last": 3}},
+		{"last page", 25, Paging{Count: 10, Page: 3}, map[string]int{"self": 3, "first": 1, "previous": 2, "last": 3}},
+		{"past the end clamps previous to last", 25, Paging{Count: 10, Page: 7}, map[string]int{"self": 7, "first": 1, "previous": 3, "last": 3}},
+		{"empty set", 0, Paging{Count: 10, Page: 1}, map[string]int{"self": 1, "first": 1, "last": 1}},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://example.com/api/Provider?name=Sunset", nil)
+		got := map[string]int{}
+		for _, link := range Links(r, tt.total, tt.p) {
+			u := httptest.NewRequest("GET", link.URL, nil).URL.Query()
+			if u.Get("name") != "Sunset" || u.Get("_count") != strconv.Itoa(tt.p.Count) {
+				t.Errorf("%s: %s link %s does not keep the filters", tt.name, link.Relation, link.URL)
+			}
+			got[link.Relation], _ = strconv.Atoi(u.Get("page"))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: links %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tokens
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Claims describes what an issued access token is authorised for
+type Claims struct {
+	ClientID string
+	// Restricted is set when the token is bound to organisations; unrestricted
+	// tokens may access every organisation
+	Restricted bool
+	// Organisations lists the provider (PRV-) IDs a restricted token may access
+	Organisations []string
+	IssuedAt      time.Time
+	// ExpiresAt is when the token stops being accepted; zero never expires
+	ExpiresAt time.Time
+}
+
+// AllowsOrganization reports whether the claims grant access to an organisation
+func (c Claims) AllowsOrganization(organizationID string) bool {
+	if !c.Restricted {
+		return true
+	}
+	for _, org := range c.Organisations {
+		if org == organizationID {
+			return true
+		}
+	}
+	return false
+}
+
+// Store of issued access tokens and their claims
+var (
+	mu     sync.RWMutex
+	issued = map[string]Claims{}
+)
+
+// issuedPattern matches the "mock_<client ID>_<UUID>" tokens issued by the token endpoint
+var issuedPattern = regexp.MustCompile(`^mock_.+_[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Save records the claims for an issued access token, evicting the tokens that
+// have expired by the time it was issued
+func Save(token string, claims Claims) {
+	mu.Lock()
+	defer mu.Unlock()
+	for t, c := range issued {
+		if !c.ExpiresAt.IsZero() && !claims.IssuedAt.Before(c.ExpiresAt) {
+			delete(issued, t)
+		}
+	}
+	issued[token] = claims
+}
+
+// Lookup returns the claims for an access token issued by this server
+func Lookup(token string) (Claims, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	claims, ok := issued[token]
+	return claims, ok
+}
+
+// Delete evicts an access token
+func Delete(token string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(issued, token)
+}
+
+// DeleteClient evicts every access token issued to a client, returning how many there were
+func DeleteClient(clientID string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	n := 0
+	for t, c := range issued {
+		if c.ClientID == clientID {
+			delete(issued, t)
+			n++
+		}
+	}
+	return n
+}
+
+// Issued reports whether a token has the form of one issued by the token
+// endpoint, so that it is rejected once evicted rather than treated as an
+// unrestricted mock token
+func Issued(token string) bool {
+	return issuedPattern.MatchString(token)
+}
+
+type contextKey struct{}
+
+// WithClaims returns a copy of ctx carrying the token claims
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the token claims stored by the auth middleware.
+// Requests without claims are unrestricted.
+func FromContext(ctx context.Context) Claims {
+	claims, _ := ctx.Value(contextKey{}).(Claims)
+	return claims
+}
+
+// AllowsOrganization reports whether the caller's token grants access to an organisation
+func AllowsOrganization(ctx context.Context, organizationID string) bool {
+	return FromContext(ctx).AllowsOrganization(organizationID)
+}
@@ -0,0 +1,379 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/clock"
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// Events delivered to webhooks
+const (
+	ClientRegistered             = "client.registered"
+	QuestionnaireResponseCreated = "questionnaire-response.created"
+	QuestionnaireResponseAmended = "questionnaire-response.amended"
+	SubmissionStatusChanged      = "submission.status-changed"
+)
+
+// Headers sent with each delivery. The signature is "sha256=" followed by the
+// hex HMAC-SHA256, keyed by the webhook secret, of the timestamp header value,
+// a '.' and the body.
+const (
+	EventHeader     = "X-Mock-Event"
+	DeliveryHeader  = "X-Mock-Delivery"
+	TimestampHeader = "X-Mock-Timestamp"
+	SignatureHeader = "X-Mock-Signature"
+)
+
+// Defaults for webhooks that do not set their retry policy
+const (
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+)
+
+// maxBackoff caps the doubling of the wait between attempts
+const maxBackoff = time.Minute
+
+// maxDeliveries is the number of recent deliveries kept
+const maxDeliveries = 200
+
+// events lists the valid event types
+var events = map[string]bool{
+	ClientRegistered:             true,
+	QuestionnaireResponseCreated: true,
+	QuestionnaireResponseAmended: true,
+	SubmissionStatusChanged:      true,
+}
+
+// Store of webhooks and their recent deliveries, oldest first
+var (
+	mu             sync.Mutex
+	webhooks       []models.Webhook
+	deliveries     []*models.WebhookDelivery
+	nextWebhookID  = 1
+	nextDeliveryID = 1
+	nextEventID    = 1
+
+	// clk timestamps events and attempts
+	clk clock.Clock = clock.System
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+// LoadFromEnv sets the clock and loads the webhooks in the file named by
+// MOCK_WEBHOOKS_FILE, if set
+func LoadFromEnv(c clock.Clock) {
+	clk = c
+	path := os.Getenv("MOCK_WEBHOOKS_FILE")
+	if path == "" {
+		return
+	}
+	if err := LoadFile(path); err != nil {
+		log.Printf("Ignoring MOCK_WEBHOOKS_FILE: %v", err)
+		return
+	}
+	log.Printf("Loaded %d webhook(s) from %s", len(Webhooks()), path)
+}
+
+// LoadFile adds the JSON array of webhooks in a file
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var list []models.Webhook
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid webhooks in %s: %w", path, err)
+	}
+	for i, hook := range list {
+		if _, err := Add(hook); err != nil {
+			return fmt.Errorf("webhook %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks a webhook's URL, secret, events and retry policy
+func Validate(hook models.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if hook.Secret == "" {
+		return fmt.Errorf("secret is required to sign the deliveries")
+	}
+	for _, event := range hook.Events {
+		if !events[event] {
+			return fmt.Errorf("unknown event '%s', must be one of %s, %s, %s or %s", event,
+				ClientRegistered, QuestionnaireResponseCreated, QuestionnaireResponseAmended, SubmissionStatusChanged)
+		}
+	}
+	if hook.MaxAttempts < 0 {
+		return fmt.Errorf("maxAttempts cannot be negative")
+	}
+	if hook.Backoff != "" {
+		if d, err := time.ParseDuration(hook.Backoff); err != nil || d < 0 {
+			return fmt.Errorf("invalid backoff '%s', must be a duration such as \"2s\"", hook.Backoff)
+		}
+	}
+	return nil
+}
+
+// Add validates and stores a webhook, returning it with its assigned ID
+func Add(hook models.Webhook) (models.Webhook, error) {
+	if err := Validate(hook); err != nil {
+		return hook, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, existing := range webhooks {
+		if hook.ID != "" && existing.ID == hook.ID {
+			return hook, fmt.Errorf("webhook %s already exists", hook.ID)
+		}
+	}
+	if hook.ID == "" {
+		hook.ID = fmt.Sprintf("webhook-%d", nextWebhookID)
+		nextWebhookID++
+	}
+	webhooks = append(webhooks, hook)
+	return hook, nil
+}
+
+// Webhooks returns copies of the stored webhooks
+func Webhooks() []models.Webhook {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]models.Webhook{}, webhooks...)
+}
+
+// Delete removes a webhook, reporting whether it existed. Pending deliveries
+// to it are still attempted.
+func Delete(id string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, hook := range webhooks {
+		if hook.ID == id {
+			webhooks = append(webhooks[:i], webhooks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Clear removes every webhook
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	webhooks = nil
+}
+
+// Emit delivers an event to every webhook subscribed to it, in the background
+func Emit(eventType string, data any) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var subscribed []models.Webhook
+	for _, hook := range webhooks {
+		if subscribes(hook, eventType) {
+			subscribed = append(subscribed, hook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	// Encode the data now, as the caller may change it once Emit returns
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Could not encode %s event: %v", eventType, err)
+		return
+	}
+	event := models.WebhookEvent{
+		ID:   fmt.Sprintf("evt-%d", nextEventID),
+		Type: eventType,
+		Time: clk.Now(),
+		Data: json.RawMessage(encoded),
+	}
+	nextEventID++
+	body, _ := json.Marshal(event)
+
+	for _, hook := range subscribed {
+		delivery := &models.WebhookDelivery{
+			ID:        nextDeliveryID,
+			WebhookID: hook.ID,
+			URL:       hook.URL,
+			Event:     event,
+			Status:    "pending",
+			Attempts:  []models.WebhookAttempt{},
+		}
+		nextDeliveryID++
+		deliveries = append(deliveries, delivery)
+		if len(deliveries) > maxDeliveries {
+			deliveries = append([]*models.WebhookDelivery(nil), deliveries[len(deliveries)-maxDeliveries:]...)
+		}
+		go deliver(hook, delivery, body)
+	}
+}
+
+func subscribes(hook models.Webhook, eventType string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, event := range hook.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver posts an event until a 2xx response or the webhook's attempts run out
+func deliver(hook models.Webhook, delivery *models.WebhookDelivery, body []byte) {
+	maxAttempts := hook.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	backoff := defaultBackoff
+	if hook.Backoff != "" {
+		backoff, _ = time.ParseDuration(hook.Backoff)
+	}
+
+	for n := 1; ; n++ {
+		attempt := post(hook, delivery, body)
+		ok := attempt.StatusCode >= 200 && attempt.StatusCode < 300
+
+		mu.Lock()
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		switch {
+		case ok:
+			delivery.Status = "delivered"
+		case n >= maxAttempts:
+			delivery.Status = "failed"
+		}
+		mu.Unlock()
+
+		if ok {
+			return
+		}
+		if n >= maxAttempts {
+			log.Printf("Webhook %s gave up on delivery %d of %s after %d attempt(s)", hook.ID, delivery.ID, delivery.Event.Type, n)
+			return
+		}
+		time.Sleep(backoff)
+		if backoff < maxBackoff {
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+// post makes one delivery attempt
+func post(hook models.Webhook, delivery *models.WebhookDelivery, body []byte) (attempt models.WebhookAttempt) {
+	attempt.Time = clk.Now()
+	started := time.Now()
+	defer func() {
+		attempt.DurationMs = float64(time.Since(started).Microseconds()) / 1000
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := strconv.FormatInt(attempt.Time.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	resp.Body.Close()
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = "unexpected status " + resp.Status
+	}
+	return attempt
+}
+
+// Sign returns the signature header value for a delivery body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliveryFilter selects deliveries; zero fields match everything
+type DeliveryFilter struct {
+	WebhookID string
+	Event     string
+	Status    string
+	// Limit keeps only the most recent matching deliveries
+	Limit int
+}
+
+// Deliveries returns copies of the recent deliveries matching a filter, oldest first
+func Deliveries(f DeliveryFilter) []models.WebhookDelivery {
+	mu.Lock()
+	defer mu.Unlock()
+
+	matched := []models.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		if f.WebhookID != "" && delivery.WebhookID != f.WebhookID {
+			continue
+		}
+		if f.Event != "" && delivery.Event.Type != f.Event {
+			continue
+		}
+		if f.Status != "" && delivery.Status != f.Status {
+			continue
+		}
+		matched = append(matched, snapshot(delivery))
+	}
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+	return matched
+}
+
+// Delivery returns a copy of a recent delivery by ID
+func Delivery(id int) (models.WebhookDelivery, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, delivery := range deliveries {
+		if delivery.ID == id {
+			return snapshot(delivery), true
+		}
+	}
+	return models.WebhookDelivery{}, false
+}
+
+// ClearDeliveries forgets the recorded deliveries; pending ones still complete
+func ClearDeliveries() {
+	mu.Lock()
+	defer mu.Unlock()
+	deliveries = nil
+}
+
+// snapshot copies a delivery. Callers must hold mu.
+func snapshot(delivery *models.WebhookDelivery) models.WebhookDelivery {
+	c := *delivery
+	c.Attempts = append([]models.WebhookAttempt{}, delivery.Attempts...)
+	return c
+}
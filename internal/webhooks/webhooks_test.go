@@ -0,0 +1,143 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonchiu/dohac-mock-apis/internal/models"
+)
+
+// receiver is a webhook endpoint failing the first failures calls, checking
+// each call's signature with secret
+type receiver struct {
+	t        *testing.T
+	secret   string
+	failures int
+
+	mu     sync.Mutex
+	calls  int
+	events []models.WebhookEvent
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if got, want := r.Header.Get(SignatureHeader), Sign(rc.secret, r.Header.Get(TimestampHeader), body); got != want {
+		rc.t.Errorf("got signature %s, want %s", got, want)
+	}
+	var event models.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || r.Header.Get(EventHeader) != event.Type {
+		rc.t.Errorf("got event %s with %s header %q (%v)", body, EventHeader, r.Header.Get(EventHeader), err)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.calls++
+	rc.events = append(rc.events, event)
+	if rc.calls <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// reset removes every webhook and delivery
+func reset() {
+	Clear()
+	ClearDeliveries()
+}
+
+// settled waits for a webhook's delivery to be delivered or to fail
+func settled(t *testing.T, webhookID string) models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		list := Deliveries(DeliveryFilter{WebhookID: webhookID})
+		if len(list) == 1 && list[0].Status != "pending" {
+			return list[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("delivery to %s did not finish", webhookID)
+	return models.WebhookDelivery{}
+}
+
+func TestDeliverSignsAndRetries(t *testing.T) {
+	reset()
+	defer reset()
+
+	tests := []struct {
+		name        string
+		failures    int
+		maxAttempts int
+		status      string
+		calls       int
+	}{
+		{"first try", 0, 3, "delivered", 1},
+		{"after retries", 2, 3, "delivered", 3},
+		{"gives up", 5, 3, "failed", 3},
+	}
+	for _, tt := range tests {
+		rc := &receiver{t: t, secret: "s3cret-" + tt.name, failures: tt.failures}
+		server := httptest.NewServer(rc)
+		defer server.Close()
+
+		hook, err := Add(models.Webhook{
+			URL:         server.URL,
+			Secret:      rc.secret,
+			Events:      []string{QuestionnaireResponseCreated},
+			MaxAttempts: tt.maxAttempts,
+			Backoff:     "1ms",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		Emit(QuestionnaireResponseAmended, map[string]string{"id": "QR-1"})
+		Emit(QuestionnaireResponseCreated, map[string]string{"id": "QR-1"})
+
+		delivery := settled(t, hook.ID)
+		rc.mu.Lock()
+		calls, events := rc.calls, rc.events
+		rc.mu.Unlock()
+
+		if delivery.Status != tt.status || len(delivery.Attempts) != tt.calls || calls != tt.calls {
+			t.Errorf("%s: got %s after %d attempts and %d calls, want %s after %d", tt.name, delivery.Status, len(delivery.Attempts), calls, tt.status, tt.calls)
+		}
+		for _, event := range events {
+			if event.Type != QuestionnaireResponseCreated || event.ID != delivery.Event.ID {
+				t.Errorf("%s: got event %s %s, want %s %s", tt.name, event.ID, event.Type, delivery.Event.ID, QuestionnaireResponseCreated)
+			}
+		}
+		Delete(hook.ID)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		hook models.Webhook
+		ok   bool
+	}{
+		{"valid", models.Webhook{URL: "http://localhost:9000/hook", Secret: "s3cret"}, true},
+		{"no secret", models.Webhook{URL: "http://localhost:9000/hook"}, false},
+		{"relative url", models.Webhook{URL: "/hook", Secret: "s3cret"}, false},
+		{"unknown event", models.Webhook{URL: "http://localhost:9000/hook", Secret: "s3cret", Events: []string{"provider.created"}}, false},
+		{"negative attempts", models.Webhook{URL: "http://localhost:9000/hook", Secret: "s3cret", MaxAttempts: -1}, false},
+		{"bad backoff", models.Webhook{URL: "http://localhost:9000/hook", Secret: "s3cret", Backoff: "soon"}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.hook); (err == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 of "1700000000." and the body, keyed by "s3cret"
+	want := "sha256=f7b35f99c3c73ae3a64b476d5b02dc6f76817ee112a0e301af57acc376aae7a7"
+	if got := Sign("s3cret", "1700000000", []byte(`{"id":"evt-1"}`)); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}